@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"context"
+	"time"
+)
+
+// DailyActivity is what a player did in a mode on a single day.
+type DailyActivity struct {
+	Day              time.Time
+	Plays            int
+	Passes           int
+	TotalScore       int64
+	PPGained         float64
+	Playtime         int // seconds
+	DistinctBeatmaps int
+}
+
+// StartActivity keeps user_daily_activity up to date every ActivityInterval.
+func (c *Collector) StartActivity(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.Config.ActivityInterval)
+		defer ticker.Stop()
+
+		for {
+			updated, err := c.Store.AggregateActivity(ctx, c.Config.ActivityBatch)
+			if err != nil {
+				c.Logger.Error("Couldn't aggregate activity", "error", err)
+			} else {
+				c.Logger.Debug("Aggregated activity", "days", updated)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
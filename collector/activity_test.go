@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregateActivity(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.AddDate(0, 0, -1)
+
+	scoreID := 0
+	score := func(at time.Time, beatmap int, passed bool) {
+		t.Helper()
+		scoreID++
+		if _, err := db.Pool().Exec(ctx, `
+		INSERT INTO scores (user_id, beatmap, score_id, score, accuracy, fc, time, passed, mode)
+		VALUES (2, $1, $2, 1000, 100, false, $3, $4, 0)`,
+			beatmap, scoreID, at, passed,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	score(yesterday.Add(time.Hour), 75, true)
+	score(today.Add(time.Hour), 75, true)
+	score(today.Add(2*time.Hour), 75, false)
+	score(today.Add(3*time.Hour), 76, true)
+	seedStats(t, db, testStats{UserID: 2, Day: yesterday.AddDate(0, 0, -1), PP: 1000, Playtime: 3600})
+	seedStats(t, db, testStats{UserID: 2, Day: today, PP: 1010, Playtime: 4200})
+
+	if _, err := db.AggregateActivity(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(want ...DailyActivity) {
+		t.Helper()
+		days, err := db.Activity(ctx, 2, 0, yesterday, today)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(days) != len(want) {
+			t.Fatalf("expected %d days, got %+v", len(want), days)
+		}
+		for i, w := range want {
+			d := days[i]
+			if !d.Day.Equal(w.Day) || d.Plays != w.Plays || d.Passes != w.Passes || d.TotalScore != w.TotalScore ||
+				d.PPGained != w.PPGained || d.Playtime != w.Playtime || d.DistinctBeatmaps != w.DistinctBeatmaps {
+				t.Errorf("expected %+v, got %+v", w, d)
+			}
+		}
+	}
+
+	check(
+		DailyActivity{Day: yesterday, Plays: 1, Passes: 1, TotalScore: 1000, DistinctBeatmaps: 1},
+		DailyActivity{Day: today, Plays: 3, Passes: 2, TotalScore: 3000, PPGained: 10, Playtime: 600, DistinctBeatmaps: 2},
+	)
+
+	// Only the new score and today's stats have to be picked up
+	score(today.Add(4*time.Hour), 77, true)
+	seedStats(t, db, testStats{UserID: 2, Day: today, PP: 1020, Playtime: 4500})
+
+	if _, err := db.AggregateActivity(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	check(
+		DailyActivity{Day: yesterday, Plays: 1, Passes: 1, TotalScore: 1000, DistinctBeatmaps: 1},
+		DailyActivity{Day: today, Plays: 4, Passes: 3, TotalScore: 4000, PPGained: 20, Playtime: 900, DistinctBeatmaps: 3},
+	)
+}
@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+// UpdateBest snapshots the top 100 and stores the first place scores of a
+// player in every mode set in modes. Every snapshot is recorded, even an empty
+// one, so the player isn't pending again until the interval passed. It is the
+// flush of the best queue.
+func (c *Collector) UpdateBest(ctx context.Context, id int, modes uint8) error {
+	for i := 0; i < 4; i++ {
+		if modes&(1<<i) == 0 {
+			continue
+		}
+
+		best, err := c.GetBest(ctx, id, osuapi.ModeStr(i))
+		if errors.Is(err, osuapi.ErrNotFound) {
+			// Restricted players have no best scores, which is recorded like
+			// any other snapshot so they aren't queued again on every run.
+			if err := c.Store.SnapshotBest(ctx, id, i, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := c.storeScores(ctx, best, SourceBest); err != nil {
+			return err
+		}
+
+		if err := c.Store.SnapshotBest(ctx, id, i, best); err != nil {
+			return err
+		}
+
+		firsts, err := c.GetFirsts(ctx, id, osuapi.ModeStr(i))
+		if err != nil {
+			return err
+		}
+
+		if err := c.storeScores(ctx, firsts, SourceFirsts); err != nil {
+			return err
+		}
+
+		c.Logger.Debug("Snapshotted best scores", "user_id", id, "mode", i, "best", len(best), "firsts", len(firsts))
+	}
+
+	return nil
+}
+
+// queueBest queues every mode a tracked user has stats in that wasn't
+// snapshotted within the given amount of days.
+func (c *Collector) queueBest(ctx context.Context, days int) {
+	pending, err := c.Store.PendingBest(ctx, days)
+	if err != nil {
+		c.Logger.Error("Couldn't load users for best snapshots", "error", err)
+		return
+	}
+
+	c.Logger.Info("Queued best snapshots", "count", len(pending))
+
+	for _, p := range pending {
+		c.bestUpdater.Queue(p.UserID, uint8(p.Mode), false)
+	}
+}
+
+func (c *Collector) scheduleBest(ctx context.Context, days int) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		c.queueBest(ctx, days)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+func TestPendingBestRecordsEmptySnapshots(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	rank := 1000
+	for _, id := range []int{2, 3} {
+		if err := db.CreateUser(ctx, &osuapi.UserExtended{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+		for mode := range 2 {
+			if err := db.UpdateHistory(ctx, id, mode, &osuapi.UserStatistics{GlobalRank: &rank}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	pending, err := db.PendingBest(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 4 {
+		t.Fatalf("expected both modes of both players to be pending, got %v", pending)
+	}
+
+	// A player without any best scores is done once the snapshot was tried
+	if err := db.SnapshotBest(ctx, 3, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err = db.PendingBest(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the snapshotted mode is done, the other one still needs its own
+	if len(pending) != 3 || slices.Contains(pending, UserMode{3, 0}) {
+		t.Fatalf("expected everything but mode 0 of player 3 to be pending, got %v", pending)
+	}
+}
+
+func TestUpdateBestNotFound(t *testing.T) {
+	f := newFakeOsu(t)
+	store := newMemStore()
+	c, _ := testCollector(t, f, store)
+
+	f.Status("/users/2/scores/best", http.StatusNotFound)
+
+	if err := c.UpdateBest(t.Context(), 2, 1<<osuapi.ModeStd|1<<osuapi.ModeTaiko); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every mode is recorded, not just the first one that wasn't found
+	for _, mode := range []uint8{osuapi.ModeStd, osuapi.ModeTaiko} {
+		if _, exists := store.best[UserMode{2, int(mode)}]; !exists {
+			t.Errorf("expected an empty snapshot of mode %d", mode)
+		}
+	}
+}
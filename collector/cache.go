@@ -1,4 +1,4 @@
-package main
+package collector
 
 import (
 	"sync"
@@ -84,5 +84,3 @@ func (t *TTLMap[K, V]) expirer() {
 		t.mu.Unlock()
 	}
 }
-
-var scoreCache = TimedCache[int, struct{}](time.Hour * 24)
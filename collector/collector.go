@@ -0,0 +1,276 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	discordwebhook "github.com/bensch777/discord-webhook-golang"
+	"github.com/calemy/advance-go/osuapi"
+)
+
+type Config struct {
+	IncludeFailed bool
+	Workers       int           // concurrent user updates
+	CursorFile    string        // where the /scores cursor is persisted
+	FetchInterval time.Duration // between /scores polls
+	FetchTimeout  time.Duration // for a single /scores poll
+	UpdateTimeout time.Duration // for a single user or best update
+
+	PartitionMonths int // how many monthly score partitions exist ahead of time
+
+	LeaderboardSize     int           // players on every leaderboard snapshot
+	LeaderboardInterval time.Duration // between leaderboard snapshots
+
+	ActivityInterval time.Duration // between aggregating the daily activity
+	ActivityBatch    int           // score ids aggregated at once
+
+	GainsInterval time.Duration // between computing the gains
+
+	MilestoneRules []MilestoneRule
+
+	RetentionRules    []RetentionRule
+	RetentionInterval time.Duration // between applying the retention rules
+	ArchiveDir        string        // where scores are archived before they get deleted
+
+	QuarantineDir   string // where unparseable payloads are kept
+	QuarantineFiles int    // how many of them are kept
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Workers:       20,
+		CursorFile:    "cursor.txt",
+		FetchInterval: 15 * time.Second,
+		FetchTimeout:  time.Minute,
+		UpdateTimeout: 5 * time.Minute,
+
+		PartitionMonths: 3,
+
+		LeaderboardSize:     100,
+		LeaderboardInterval: time.Hour,
+
+		ActivityInterval: 10 * time.Minute,
+		ActivityBatch:    100000,
+
+		GainsInterval: time.Hour,
+
+		RetentionInterval: 24 * time.Hour,
+		ArchiveDir:        "archive",
+
+		QuarantineDir:   "quarantine",
+		QuarantineFiles: 200,
+	}
+}
+
+// Collector polls the global score feed, stores every score and keeps the
+// stats of every player it has seen up to date.
+type Collector struct {
+	Config Config
+
+	Store        Store
+	API          API
+	Stats        Notifier            // hourly collection stats
+	Restrictions Notifier            // players that got restricted
+	Milestones   map[string]Notifier // by the webhook of the milestone rules
+	Logger       *slog.Logger
+
+	quarantine  *Quarantine
+	cursor      string
+	userCache   *UserCache
+	scoreCache  *TTLMap[int, struct{}]
+	userUpdater *Queue
+	bestUpdater *Queue
+
+	userCount  atomic.Int64
+	scoreCount atomic.Int64
+	statsCount atomic.Int64
+}
+
+func New(cfg Config, store Store, api API, stats Notifier, restrictions Notifier) *Collector {
+	c := &Collector{
+		Config:       cfg,
+		Store:        store,
+		API:          api,
+		Stats:        stats,
+		Restrictions: restrictions,
+		Logger:       slog.Default().With("component", "collector"),
+		quarantine:   &Quarantine{Dir: cfg.QuarantineDir, MaxFiles: cfg.QuarantineFiles},
+		userCache:    &UserCache{m: make(map[int]struct{})},
+		scoreCache:   TimedCache[int, struct{}](time.Hour * 24),
+	}
+
+	c.userUpdater = createQueue(c.UpdateUser, 512, 256)
+	c.bestUpdater = createQueue(c.UpdateBest, 512, 64)
+	c.userUpdater.Timeout = cfg.UpdateTimeout
+	c.bestUpdater.Timeout = cfg.UpdateTimeout
+
+	return c
+}
+
+// Start loads the tracked users and pending updates and begins polling the
+// score feed. It returns once everything runs in the background, which stops
+// when ctx is done.
+func (c *Collector) Start(ctx context.Context) error {
+	c.loadCursor()
+
+	if err := c.CreatePartitions(ctx); err != nil {
+		return err
+	}
+	go c.schedulePartitions(ctx)
+
+	c.userUpdater.Workers(ctx, c.Config.Workers)
+	c.userUpdater.Start(ctx)
+
+	if err := c.LoadUsers(ctx); err != nil {
+		return err
+	}
+
+	if err := c.LoadQueue(ctx); err != nil {
+		return err
+	}
+
+	c.poll(ctx) // 4 * 1 Ratelimit -> 4 -> 604
+
+	go func() {
+		ticker := time.NewTicker(c.Config.FetchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.poll(ctx)
+			}
+		}
+	}()
+
+	go c.reportStats(ctx)
+
+	return nil
+}
+
+// StartBest snapshots the best scores of every player every given amount of
+// days.
+func (c *Collector) StartBest(ctx context.Context, days int) {
+	c.bestUpdater.Workers(ctx, 2)
+	c.bestUpdater.Start(ctx)
+	go c.scheduleBest(ctx, days)
+}
+
+// StartPlaycounts collects beatmap playcounts every given amount of days,
+// using at most perMinute requests a minute.
+func (c *Collector) StartPlaycounts(ctx context.Context, days int, perMinute int) {
+	go c.schedulePlaycounts(ctx, days, perMinute)
+}
+
+// poll fetches the next page of the score feed within FetchTimeout.
+func (c *Collector) poll(ctx context.Context) {
+	if c.Config.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Config.FetchTimeout)
+		defer cancel()
+	}
+	c.FetchScores(ctx)
+}
+
+func (c *Collector) reportStats(ctx context.Context) {
+	now := time.Now()
+	nextHour := now.Truncate(time.Hour).Add(time.Hour)
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(time.Until(nextHour)):
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		embed := discordwebhook.Embed{
+			Title:     "Update Stats",
+			Color:     0x86DC3D,
+			Timestamp: time.Now(),
+			Footer: discordwebhook.Footer{
+				Text: fmt.Sprintf("Users tracked: %d", c.userCount.Load()),
+			},
+			Fields: []discordwebhook.Field{
+				{
+					Name:   "Scores Stored",
+					Value:  fmt.Sprintf("%d", c.scoreCount.Swap(0)),
+					Inline: true,
+				},
+				{
+					Name:   "Stats Updated",
+					Value:  fmt.Sprintf("%d", c.statsCount.Swap(0)),
+					Inline: true,
+				},
+			},
+		}
+		hook := discordwebhook.Hook{
+			Username:   "Advance",
+			Avatar_url: "https://a.ppy.sh/9527931",
+			Embeds:     []discordwebhook.Embed{embed},
+		}
+		c.Stats.Queue(hook)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// quarantinePayload keeps the payload of a response that couldn't be decoded.
+// It reports whether err was such an error.
+func (c *Collector) quarantinePayload(err error) bool {
+	var decodeErr *osuapi.DecodeError
+	if !errors.As(err, &decodeErr) {
+		return false
+	}
+
+	path, qErr := c.quarantine.Save(decodeErr.Endpoint, decodeErr.Body, decodeErr.Err)
+	if qErr != nil {
+		c.Logger.Error("Couldn't quarantine payload", "endpoint", decodeErr.Endpoint, "error", qErr)
+		return true
+	}
+
+	c.Logger.Error("Quarantined unparseable payload", "endpoint", decodeErr.Endpoint, "file", path, "error", decodeErr.Err)
+	return true
+}
+
+func (c *Collector) LoadUsers(ctx context.Context) error {
+	ids, err := c.Store.LoadUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		c.userCache.Add(id)
+	}
+
+	c.userCount.Store(int64(len(ids)))
+	c.Logger.Info("Loaded users", "count", len(ids))
+	return nil
+}
+
+// LoadQueue queues every user that set a score after their last update.
+func (c *Collector) LoadQueue(ctx context.Context) error {
+	pending, err := c.Store.LoadQueue(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		go c.userUpdater.Queue(p.UserID, uint8(p.Mode), true)
+	}
+
+	c.Logger.Info("Queued updates", "count", len(pending))
+	return nil
+}
@@ -0,0 +1,30 @@
+package collector
+
+import (
+	"io"
+	"os"
+)
+
+func (c *Collector) loadCursor() {
+	file, err := os.OpenFile(c.Config.CursorFile, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	defer file.Close()
+
+	csr, err := io.ReadAll(file)
+	if err != nil {
+		c.Logger.Error("Couldn't read the cursor file", "file", c.Config.CursorFile, "error", err)
+		return
+	}
+
+	c.cursor = string(csr)
+}
+
+func (c *Collector) saveCursor(cursor string) {
+	c.cursor = cursor
+	if err := os.WriteFile(c.Config.CursorFile, []byte(cursor), 0644); err != nil {
+		c.Logger.Error("Couldn't write the cursor file", "file", c.Config.CursorFile, "error", err)
+	}
+}
@@ -0,0 +1,223 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/parquet-go/parquet-go"
+)
+
+// ExportOptions selects what Export writes. Filters that are left empty
+// don't apply, the others have to be supported by the table.
+type ExportOptions struct {
+	Table  string // scores, stats, stats_base, users or beatmaps
+	Format string // csv, ndjson or parquet
+
+	Modes     []int
+	From, To  time.Time // From is inclusive, To exclusive
+	Countries []string  // ISO 3166-1 alpha-2
+	Users     []int
+}
+
+// exportTable describes which filters a table supports. Empty columns don't
+// support the filter.
+type exportTable struct {
+	time    string
+	mode    string
+	user    string
+	country string // joined through users unless the table has it itself
+}
+
+var exportTables = map[string]exportTable{
+	"scores":     {time: "time", mode: "mode", user: "user_id"},
+	"stats":      {time: "day", mode: "mode", user: "user_id"},
+	"stats_base": {time: "day", user: "user_id"},
+	"users":      {time: "added", user: "user_id", country: "country"},
+	"beatmaps":   {time: "last_update"},
+}
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// exportQuery builds the SELECT behind an export. COPY doesn't take
+// parameters, so every filter is validated and formatted into the query.
+func exportQuery(opts ExportOptions) (string, error) {
+	table, ok := exportTables[opts.Table]
+	if !ok {
+		return "", fmt.Errorf("unknown table %q", opts.Table)
+	}
+
+	conditions := make([]string, 0, 5)
+
+	if !opts.From.IsZero() || !opts.To.IsZero() {
+		if table.time == "" {
+			return "", fmt.Errorf("%s can't be filtered by date", opts.Table)
+		}
+		if !opts.From.IsZero() {
+			conditions = append(conditions, fmt.Sprintf(`t.%s >= '%s'`, table.time, opts.From.UTC().Format(time.RFC3339Nano)))
+		}
+		if !opts.To.IsZero() {
+			conditions = append(conditions, fmt.Sprintf(`t.%s < '%s'`, table.time, opts.To.UTC().Format(time.RFC3339Nano)))
+		}
+	}
+
+	if len(opts.Modes) > 0 {
+		if table.mode == "" {
+			return "", fmt.Errorf("%s can't be filtered by mode", opts.Table)
+		}
+		conditions = append(conditions, fmt.Sprintf(`t.%s IN (%s)`, table.mode, JoinInts(opts.Modes, ", ")))
+	}
+
+	if len(opts.Users) > 0 {
+		if table.user == "" {
+			return "", fmt.Errorf("%s can't be filtered by user", opts.Table)
+		}
+		conditions = append(conditions, fmt.Sprintf(`t.%s IN (%s)`, table.user, JoinInts(opts.Users, ", ")))
+	}
+
+	if len(opts.Countries) > 0 {
+		if table.country == "" && table.user == "" {
+			return "", fmt.Errorf("%s can't be filtered by country", opts.Table)
+		}
+
+		quoted := make([]string, len(opts.Countries))
+		for i, country := range opts.Countries {
+			if !countryCode.MatchString(country) {
+				return "", fmt.Errorf("invalid country code %q", country)
+			}
+			quoted[i] = "'" + country + "'"
+		}
+		countries := strings.Join(quoted, ", ")
+
+		if table.country != "" {
+			conditions = append(conditions, fmt.Sprintf(`t.%s IN (%s)`, table.country, countries))
+		} else {
+			conditions = append(conditions, fmt.Sprintf(`t.%s IN (SELECT user_id FROM users WHERE country IN (%s))`, table.user, countries))
+		}
+	}
+
+	query := fmt.Sprintf(`SELECT * FROM %s t`, opts.Table)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return query, nil
+}
+
+// Export streams the rows of a table matching opts to w and returns how many
+// were written. CSV and NDJSON are streamed by the database with COPY TO,
+// Parquet is encoded while reading the rows.
+func (p *Postgres) Export(ctx context.Context, opts ExportOptions, w io.Writer) (int64, error) {
+	query, err := exportQuery(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	var copy string
+	switch opts.Format {
+	case "csv":
+		copy = fmt.Sprintf(`COPY (%s) TO STDOUT WITH (FORMAT csv, HEADER)`, query)
+	case "ndjson":
+		// The CSV format with quote and delimiter characters that json never
+		// contains unescaped writes every object as is.
+		copy = fmt.Sprintf(`COPY (SELECT row_to_json(t) FROM (%s) t) TO STDOUT WITH (FORMAT csv, QUOTE E'\x01', DELIMITER E'\x02')`, query)
+	case "parquet":
+		return p.exportParquet(ctx, query, w)
+	default:
+		return 0, fmt.Errorf("unknown format %q", opts.Format)
+	}
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Conn().PgConn().CopyTo(ctx, w, copy)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (p *Postgres) exportParquet(ctx context.Context, query string, w io.Writer) (int64, error) {
+	rows, err := p.pool.Query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+	group := make(parquet.Group, len(fields))
+	for _, field := range fields {
+		group[field.Name] = parquet.Optional(parquetNode(field.DataTypeOID))
+	}
+
+	writer := parquet.NewWriter(w, parquet.NewSchema("row", group))
+	count := int64(0)
+
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return count, err
+		}
+
+		row := make(map[string]any, len(fields))
+		for i, field := range fields {
+			row[field.Name] = parquetValue(field.DataTypeOID, values[i])
+		}
+
+		if err := writer.Write(row); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	return count, writer.Close()
+}
+
+// parquetNode maps the column types of the schema to Parquet. Anything else
+// is written as its text.
+func parquetNode(oid uint32) parquet.Node {
+	switch oid {
+	case pgtype.BoolOID:
+		return parquet.Leaf(parquet.BooleanType)
+	case pgtype.Int2OID, pgtype.Int4OID:
+		return parquet.Int(32)
+	case pgtype.Int8OID:
+		return parquet.Int(64)
+	case pgtype.Float4OID:
+		return parquet.Leaf(parquet.FloatType)
+	case pgtype.Float8OID:
+		return parquet.Leaf(parquet.DoubleType)
+	case pgtype.TimestamptzOID, pgtype.TimestampOID, pgtype.DateOID:
+		return parquet.Timestamp(parquet.Microsecond)
+	case pgtype.TextArrayOID:
+		return parquet.List(parquet.String())
+	default:
+		return parquet.String()
+	}
+}
+
+func parquetValue(oid uint32, value any) any {
+	if value == nil {
+		return nil
+	}
+
+	switch oid {
+	case pgtype.BoolOID, pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.Float4OID, pgtype.Float8OID,
+		pgtype.TimestamptzOID, pgtype.TimestampOID, pgtype.DateOID, pgtype.TextArrayOID:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportQuery(t *testing.T) {
+	day := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	query, err := exportQuery(ExportOptions{
+		Table:     "scores",
+		Modes:     []int{0, 3},
+		From:      day,
+		To:        day.AddDate(0, 0, 1),
+		Countries: []string{"DE"},
+		Users:     []int{2, 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`FROM scores t WHERE`,
+		`t.time >= '2025-01-01T00:00:00Z'`,
+		`t.time < '2025-01-02T00:00:00Z'`,
+		`t.mode IN (0, 3)`,
+		`t.user_id IN (2, 3)`,
+		`t.user_id IN (SELECT user_id FROM users WHERE country IN ('DE'))`,
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("expected %q in %s", want, query)
+		}
+	}
+
+	query, err = exportQuery(ExportOptions{Table: "users", Countries: []string{"DE", "US"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(query, `WHERE t.country IN ('DE', 'US')`) {
+		t.Errorf("expected users to be filtered by their own country, got %s", query)
+	}
+
+	invalid := []ExportOptions{
+		{Table: "pg_authid"},
+		{Table: "beatmaps", Modes: []int{0}},
+		{Table: "beatmaps", Users: []int{2}},
+		{Table: "stats_base", Modes: []int{0}},
+		{Table: "users", Countries: []string{"DE') OR ('1"}},
+	}
+	for _, opts := range invalid {
+		if _, err := exportQuery(opts); err == nil {
+			t.Errorf("expected %+v to be rejected", opts)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	seedUser(t, db, 2, "peppy", "AU", false)
+	seedUser(t, db, 3, "Bob", "DE", false)
+
+	var csv bytes.Buffer
+	count, err := db.Export(ctx, ExportOptions{Table: "users", Format: "csv", Countries: []string{"AU"}}, &csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if count != 1 || len(lines) != 2 || !strings.HasPrefix(lines[0], "id,user_id,username") {
+		t.Fatalf("expected a header and peppy, got %d rows:\n%s", count, csv.String())
+	}
+
+	var ndjson bytes.Buffer
+	count, err = db.Export(ctx, ExportOptions{Table: "users", Format: "ndjson"}, &ndjson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines = strings.Split(strings.TrimSpace(ndjson.String()), "\n")
+	if count != 2 || len(lines) != 2 {
+		t.Fatalf("expected 2 objects, got %d rows:\n%s", count, ndjson.String())
+	}
+	for _, line := range lines {
+		var user struct {
+			UserID   int    `json:"user_id"`
+			Username string `json:"username"`
+		}
+		if err := json.Unmarshal([]byte(line), &user); err != nil || user.UserID == 0 {
+			t.Errorf("invalid line %s: %v", line, err)
+		}
+	}
+
+	var parquet bytes.Buffer
+	count, err = db.Export(ctx, ExportOptions{Table: "users", Format: "parquet"}, &parquet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || !bytes.HasPrefix(parquet.Bytes(), []byte("PAR1")) {
+		t.Fatalf("expected a parquet file with 2 rows, got %d", count)
+	}
+}
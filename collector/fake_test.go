@@ -0,0 +1,393 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	discordwebhook "github.com/bensch777/discord-webhook-golang"
+	"github.com/calemy/advance-go/osuapi"
+	"github.com/calemy/advance-go/osuapi/osuapitest"
+)
+
+// fakeOsu serves the osu! API in-process, with a client pointing at it.
+type fakeOsu struct {
+	*osuapitest.Server
+	Client *osuapi.OsuClient
+}
+
+func newFakeOsu(t *testing.T) *fakeOsu {
+	t.Helper()
+
+	f := &fakeOsu{Server: osuapitest.New(t)}
+	f.Client = osuapi.NewOsuClient(osuapi.NewClient(100, nil), "id", "secret")
+	f.Client.BaseURL = f.BaseURL
+	f.Client.AuthURL = f.AuthURL
+	f.Client.Backoff = time.Millisecond
+
+	return f
+}
+
+// testDB connects to TEST_POSTGRES_URL, which has to point to a throwaway
+// database. It is migrated to the newest schema and every table is truncated.
+func testDB(t *testing.T) *Postgres {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_URL")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_URL not set")
+	}
+
+	db, err := NewPostgres(t.Context(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	if _, err := db.Migrate(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Pool().Exec(t.Context(), `
+	TRUNCATE scores, stats, stats_base, users, beatmaps, user_best, user_playcounts, leaderboards, user_daily_activity, aggregation_state, user_gains, milestones_sent, best_snapshots RESTART IDENTITY`,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// seedUser inserts a user into the test database.
+func seedUser(t *testing.T, db *Postgres, id int, username string, country string, restricted bool) {
+	t.Helper()
+
+	if _, err := db.Pool().Exec(t.Context(), `
+	INSERT INTO users (user_id, username, country, restricted) VALUES ($1, $2, $3, $4::boolean::integer)`,
+		id, username, country, restricted,
+	); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testStats is a row of stats for seedStats. The columns left out have no
+// meaning for the tests and are set to 0, accuracy to 98.
+type testStats struct {
+	UserID    int
+	Mode      int
+	Day       time.Time
+	PP        float64
+	Global    int // unranked if 0
+	Playcount int
+	Playtime  int
+}
+
+// seedStats inserts the stats of a user and day into the test database,
+// replacing the ones already there.
+func seedStats(t *testing.T, db *Postgres, s testStats) {
+	t.Helper()
+
+	global := s.Global
+	if global == 0 {
+		global = 999999999
+	}
+
+	if _, err := db.Pool().Exec(t.Context(), `
+	INSERT INTO stats (user_id, mode, global, pp, accuracy, playcount, playtime, score, ranked_score, hits, level, progress, day)
+	VALUES ($1, $2, $3, $4, 98, $5, $6, 0, 0, 0, 0, 0, $7)
+	ON CONFLICT (user_id, mode, day) DO UPDATE SET
+		global = EXCLUDED.global, pp = EXCLUDED.pp, playcount = EXCLUDED.playcount, playtime = EXCLUDED.playtime`,
+		s.UserID, s.Mode, global, s.PP, s.Playcount, s.Playtime, s.Day,
+	); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testCollector creates a collector on top of the fake API and the given
+// store. Webhooks are recorded instead of sent.
+func testCollector(t *testing.T, f *fakeOsu, store Store) (*Collector, *recorder) {
+	t.Helper()
+
+	hooks := &recorder{}
+
+	cfg := DefaultConfig()
+	dir := t.TempDir()
+	cfg.CursorFile = filepath.Join(dir, "cursor.txt")
+	cfg.QuarantineDir = filepath.Join(dir, "quarantine")
+
+	return New(cfg, store, f.Client, hooks, hooks), hooks
+}
+
+type recorder struct {
+	mu    sync.Mutex
+	hooks []discordwebhook.Hook
+}
+
+func (r *recorder) Queue(hook discordwebhook.Hook) {
+	r.mu.Lock()
+	r.hooks = append(r.hooks, hook)
+	r.mu.Unlock()
+}
+
+func (r *recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.hooks)
+}
+
+// memStore is an in-memory Store covering what the score feed and user
+// updates need, so their logic can be tested without Postgres.
+type memStore struct {
+	mu         sync.Mutex
+	scores     map[int]uint8
+	scoreRows  map[int]osuapi.Score
+	beatmaps   map[int]*osuapi.BeatmapExtended
+	users      map[int]*osuapi.UserExtended
+	restricted map[int]bool
+	stats      map[UserMode]map[time.Time]int
+	imported   map[UserMode]map[time.Time]bool
+	latest     map[UserMode]*osuapi.UserStatistics
+	top        map[UserMode]float64
+	milestones map[string]struct{}
+	best       map[UserMode][]osuapi.Score
+	playcounts map[int][]osuapi.BeatmapPlaycount
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		scores:     make(map[int]uint8),
+		scoreRows:  make(map[int]osuapi.Score),
+		beatmaps:   make(map[int]*osuapi.BeatmapExtended),
+		users:      make(map[int]*osuapi.UserExtended),
+		restricted: make(map[int]bool),
+		stats:      make(map[UserMode]map[time.Time]int),
+		imported:   make(map[UserMode]map[time.Time]bool),
+		latest:     make(map[UserMode]*osuapi.UserStatistics),
+		top:        make(map[UserMode]float64),
+		milestones: make(map[string]struct{}),
+		best:       make(map[UserMode][]osuapi.Score),
+		playcounts: make(map[int][]osuapi.BeatmapPlaycount),
+	}
+}
+
+func (m *memStore) InsertScore(ctx context.Context, s *osuapi.Score, source uint8) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.scores[s.ID]; !exists {
+		m.scores[s.ID] = source
+		m.scoreRows[s.ID] = *s
+	}
+	return nil
+}
+
+func (m *memStore) InsertBeatmap(ctx context.Context, b *osuapi.BeatmapExtended, s *osuapi.Beatmapset) error {
+	m.mu.Lock()
+	m.beatmaps[b.ID] = b
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memStore) KnownScores(ctx context.Context, ids []int) (map[int]struct{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	known := make(map[int]struct{})
+	for _, id := range ids {
+		if _, exists := m.scores[id]; exists {
+			known[id] = struct{}{}
+		}
+	}
+	return known, nil
+}
+
+func (m *memStore) StoredScores(ctx context.Context, id int, mode int, from time.Time, to time.Time) ([]StoredScore, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stored []StoredScore
+	for scoreID, s := range m.scoreRows {
+		if s.UserID != id || s.RulesetID != mode || s.EndedAt.Before(from) || s.EndedAt.After(to) {
+			continue
+		}
+		source := int16(m.scores[scoreID])
+		stored = append(stored, StoredScore{ID: scoreID, Time: s.EndedAt, Source: &source})
+	}
+	return stored, nil
+}
+
+func (m *memStore) CreatePartitions(ctx context.Context, from time.Time, months int) (int, error) {
+	return 0, nil
+}
+
+func (m *memStore) ArchiveScores(ctx context.Context, rule RetentionRule, cutoff time.Time, open func(partition string) (Archive, error)) (int64, error) {
+	return 0, nil
+}
+
+func (m *memStore) CreateUser(ctx context.Context, u *osuapi.UserExtended) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.users[u.ID]; !exists {
+		m.users[u.ID] = u
+	}
+	return nil
+}
+
+func (m *memStore) UpdateUser(ctx context.Context, u *osuapi.UserExtended) error {
+	m.mu.Lock()
+	m.users[u.ID] = u
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memStore) RestrictUser(ctx context.Context, id int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.restricted[id] = true
+	if u, exists := m.users[id]; exists {
+		return u.Username, nil
+	}
+	return "", nil
+}
+
+func (m *memStore) PeakStats(ctx context.Context, id int) (osuapi.UserStatistics, error) {
+	return osuapi.UserStatistics{}, nil
+}
+
+func (m *memStore) LastUpdate(ctx context.Context, id int) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (m *memStore) LoadUsers(ctx context.Context) ([]int, error) {
+	return nil, nil
+}
+
+func (m *memStore) LoadQueue(ctx context.Context) ([]UserMode, error) {
+	return nil, nil
+}
+
+func (m *memStore) HasHistory(ctx context.Context, id int, mode int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.stats[UserMode{id, mode}]) > 0, nil
+}
+
+func (m *memStore) history(id int, mode int) (map[time.Time]int, map[time.Time]bool) {
+	key := UserMode{id, mode}
+	if m.stats[key] == nil {
+		m.stats[key] = make(map[time.Time]int)
+		m.imported[key] = make(map[time.Time]bool)
+	}
+	return m.stats[key], m.imported[key]
+}
+
+func (m *memStore) ImportRankHistory(ctx context.Context, id int, mode int, ranks []int, daysAgo []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	history, imported := m.history(id, mode)
+	for i, ago := range daysAgo {
+		day := today.AddDate(0, 0, -ago)
+		if _, exists := history[day]; !exists {
+			history[day] = ranks[i]
+			imported[day] = true
+		}
+	}
+	return nil
+}
+
+func (m *memStore) UpdateHistory(ctx context.Context, id int, mode int, stats *osuapi.UserStatistics) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.latest[UserMode{id, mode}] = stats
+
+	if stats.GlobalRank != nil {
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		history, imported := m.history(id, mode)
+		history[today] = *stats.GlobalRank
+		delete(imported, today)
+	}
+	return nil
+}
+
+func (m *memStore) UpdateBase(ctx context.Context, u *osuapi.UserExtended) error {
+	return nil
+}
+
+func (m *memStore) LatestStats(ctx context.Context, id int, mode int) (*osuapi.UserStatistics, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.latest[UserMode{id, mode}], nil
+}
+
+// TopPlay ignores exclude, tests set the top plays directly.
+func (m *memStore) TopPlay(ctx context.Context, id int, mode int, exclude int) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.top[UserMode{id, mode}], nil
+}
+
+func (m *memStore) MarkMilestone(ctx context.Context, rule string, id int, mode int, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := fmt.Sprintf("%s/%d/%d/%s", rule, id, mode, key)
+	if _, exists := m.milestones[k]; exists {
+		return false, nil
+	}
+	m.milestones[k] = struct{}{}
+	return true, nil
+}
+
+func (m *memStore) SnapshotLeaderboards(ctx context.Context, day time.Time, mode int, size int) (int64, error) {
+	return 0, nil
+}
+
+func (m *memStore) AggregateActivity(ctx context.Context, batch int) (int64, error) {
+	return 0, nil
+}
+
+func (m *memStore) ComputeGains(ctx context.Context, period string, start time.Time, end time.Time, mode int) (int64, error) {
+	return 0, nil
+}
+
+func (m *memStore) SnapshotBest(ctx context.Context, id int, mode int, scores []osuapi.Score) error {
+	m.mu.Lock()
+	m.best[UserMode{id, mode}] = scores
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memStore) PendingBest(ctx context.Context, days int) ([]UserMode, error) {
+	return nil, nil
+}
+
+func (m *memStore) UpdatePlaycounts(ctx context.Context, id int, counts []osuapi.BeatmapPlaycount) error {
+	m.mu.Lock()
+	m.playcounts[id] = counts
+	m.mu.Unlock()
+	return nil
+}
+
+// PendingPlaycounts returns the users whose playcounts were never collected.
+func (m *memStore) PendingPlaycounts(ctx context.Context, days int) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []int
+	for id := range m.users {
+		if _, collected := m.playcounts[id]; !collected && !m.restricted[id] {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
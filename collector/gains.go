@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Periods gains are computed for.
+const (
+	GainsDay   = "day"
+	GainsWeek  = "week"
+	GainsMonth = "month"
+)
+
+var gainPeriods = []string{GainsDay, GainsWeek, GainsMonth}
+
+// Gain is how much a player improved over a period.
+type Gain struct {
+	UserID    int
+	Username  string
+	Country   string
+	PP        float64
+	Rank      *int // nil if they were unranked at either end
+	Playcount int
+}
+
+// TopGainersOptions selects a top gainers list.
+type TopGainersOptions struct {
+	Period  string
+	Start   time.Time // any time within the period
+	Mode    int
+	Country string // global if empty
+	By      string // pp, rank or playcount
+	Limit   int
+}
+
+// PeriodStart returns the UTC start of the period containing t. Weeks start on
+// monday.
+func PeriodStart(period string, t time.Time) (time.Time, error) {
+	day := t.UTC().Truncate(24 * time.Hour)
+
+	switch period {
+	case GainsDay:
+		return day, nil
+	case GainsWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7), nil
+	case GainsMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown period %q", period)
+	}
+}
+
+// periodEnd returns the start of the period after the one starting at start.
+func periodEnd(period string, start time.Time) time.Time {
+	switch period {
+	case GainsWeek:
+		return start.AddDate(0, 0, 7)
+	case GainsMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// StartGains computes the gains of the current and previous periods every
+// GainsInterval, so the previous ones get their final values once the
+// last updates of them are in.
+func (c *Collector) StartGains(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.Config.GainsInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := c.ComputeGains(ctx, time.Now()); err != nil {
+				c.Logger.Error("Couldn't compute gains", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ComputeGains computes the gains of every mode for the periods containing
+// now and the ones before them.
+func (c *Collector) ComputeGains(ctx context.Context, now time.Time) error {
+	for _, period := range gainPeriods {
+		current, err := PeriodStart(period, now)
+		if err != nil {
+			return err
+		}
+		previous, _ := PeriodStart(period, current.Add(-time.Hour))
+
+		for _, start := range []time.Time{previous, current} {
+			for mode := 0; mode < 4; mode++ {
+				gains, err := c.Store.ComputeGains(ctx, period, start, periodEnd(period, start), mode)
+				if err != nil {
+					return fmt.Errorf("%s gains of %s: %w", period, start.Format("2006-01-02"), err)
+				}
+
+				c.Logger.Debug("Computed gains", "period", period, "start", start.Format("2006-01-02"), "mode", mode, "users", gains)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodStart(t *testing.T) {
+	// A sunday evening in UTC+2, which is still sunday in UTC
+	at := time.Date(2025, time.March, 16, 23, 30, 0, 0, time.FixedZone("", 2*60*60))
+
+	for period, want := range map[string]time.Time{
+		GainsDay:   time.Date(2025, time.March, 16, 0, 0, 0, 0, time.UTC),
+		GainsWeek:  time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC),
+		GainsMonth: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		start, err := PeriodStart(period, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !start.Equal(want) {
+			t.Errorf("%s: expected %s, got %s", period, want, start)
+		}
+	}
+
+	if _, err := PeriodStart("year", at); err == nil {
+		t.Error("expected an unknown period to be rejected")
+	}
+}
+
+func TestComputeGains(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+
+	seedUser(t, db, 2, "peppy", "AU", false)
+	seedUser(t, db, 3, "Bob", "DE", false)
+	seedUser(t, db, 4, "Alice", "DE", false)
+
+	yesterday := today.AddDate(0, 0, -1)
+	seedStats(t, db, testStats{UserID: 2, Day: yesterday, PP: 1000, Global: 500, Playcount: 100})
+	seedStats(t, db, testStats{UserID: 2, Day: today, PP: 1100, Global: 400, Playcount: 120})
+	seedStats(t, db, testStats{UserID: 3, Day: yesterday, PP: 2000, Playcount: 50})
+	seedStats(t, db, testStats{UserID: 3, Day: today, PP: 2050, Global: 300, Playcount: 60})
+	seedStats(t, db, testStats{UserID: 4, Day: today, PP: 500, Global: 900, Playcount: 10}) // no stats before today
+
+	c, _ := testCollector(t, newFakeOsu(t), db)
+	if err := c.ComputeGains(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	gains, err := db.TopGainers(ctx, TopGainersOptions{Period: GainsDay, Start: now, Mode: 0, By: "pp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gains) != 2 || gains[0].UserID != 2 || gains[0].PP != 100 || gains[1].UserID != 3 {
+		t.Fatalf("expected peppy ahead of Bob, got %+v", gains)
+	}
+	if gains[0].Rank == nil || *gains[0].Rank != 100 || gains[0].Playcount != 20 {
+		t.Errorf("expected peppy to gain 100 ranks and 20 plays, got %+v", gains[0])
+	}
+	if gains[1].Rank != nil {
+		t.Errorf("expected no rank gain for Bob, who was unranked, got %d", *gains[1].Rank)
+	}
+
+	country, err := db.TopGainers(ctx, TopGainersOptions{Period: GainsDay, Start: now, Mode: 0, Country: "DE", By: "pp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(country) != 1 || country[0].UserID != 3 {
+		t.Fatalf("expected only Bob in DE, got %+v", country)
+	}
+
+	if _, err := db.TopGainers(ctx, TopGainersOptions{Period: GainsDay, Start: now, By: "pp; DROP TABLE users"}); err == nil {
+		t.Error("expected an unknown gain to be rejected")
+	}
+}
@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Kinds of leaderboards that get snapshotted.
+const (
+	LeaderboardPP          = "pp"
+	LeaderboardRankedScore = "ranked_score"
+	LeaderboardPlaycount   = "playcount"
+	LeaderboardAccuracy    = "accuracy"
+)
+
+type LeaderboardEntry struct {
+	Position int
+	UserID   int
+	Value    float64
+	Change   *int // positions gained since the previous day, nil if the user is new on it
+}
+
+// StartLeaderboards snapshots the leaderboards of the current day every
+// LeaderboardInterval. Every run replaces the previous snapshot of the day,
+// so the last one of a day is what stays.
+func (c *Collector) StartLeaderboards(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.Config.LeaderboardInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := c.SnapshotLeaderboards(ctx, time.Now().UTC().Truncate(24*time.Hour)); err != nil {
+				c.Logger.Error("Couldn't snapshot leaderboards", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SnapshotLeaderboards snapshots the leaderboards of every mode on day.
+func (c *Collector) SnapshotLeaderboards(ctx context.Context, day time.Time) error {
+	for mode := 0; mode < 4; mode++ {
+		entries, err := c.Store.SnapshotLeaderboards(ctx, day, mode, c.Config.LeaderboardSize)
+		if err != nil {
+			return err
+		}
+
+		c.Logger.Debug("Snapshotted leaderboards", "mode", mode, "entries", entries)
+	}
+
+	return nil
+}
+
+// BeatmapLeaderboardOptions selects the scores of a beatmap leaderboard.
+type BeatmapLeaderboardOptions struct {
+	Beatmap int
+	Mode    int
+
+	// Mods only keeps scores with exactly this combination, in any order.
+	// nil keeps every score, an empty slice only scores without mods. CL is
+	// ignored on both sides, like the classic mod of converted stable scores.
+	Mods []string
+
+	IncludeFailed bool
+	Limit         int
+	Offset        int
+}
+
+// BeatmapScore is the best score of a player on a beatmap leaderboard.
+type BeatmapScore struct {
+	Position int
+	ScoreID  int64
+	UserID   int
+	Username string
+	Score    int64
+	Accuracy float64
+	MaxCombo int
+	PP       float64
+	Mods     []string
+	Rank     string
+	Passed   bool
+	Time     time.Time
+}
+
+// normalizeMods prepares a mod filter for comparing it with stored scores.
+func normalizeMods(mods []string) []string {
+	if mods == nil {
+		return nil
+	}
+
+	normalized := make([]string, 0, len(mods))
+	for _, mod := range mods {
+		mod = strings.ToUpper(strings.TrimSpace(mod))
+		if mod == "" || mod == "CL" || mod == "NM" {
+			continue
+		}
+		normalized = append(normalized, mod)
+	}
+	return normalized
+}
@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotLeaderboards(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.AddDate(0, 0, -1)
+
+	seedUser(t, db, 2, "peppy", "AU", false)
+	seedUser(t, db, 3, "Bob", "DE", false)
+	seedUser(t, db, 4, "Alice", "DE", false)
+	seedUser(t, db, 5, "Restricted", "DE", true)
+
+	seedStats(t, db, testStats{UserID: 2, Day: yesterday, PP: 3000})
+	seedStats(t, db, testStats{UserID: 3, Day: yesterday, PP: 2000})
+	seedStats(t, db, testStats{UserID: 4, Day: yesterday, PP: 1000})
+	seedStats(t, db, testStats{UserID: 5, Day: yesterday, PP: 9000})
+	seedStats(t, db, testStats{UserID: 4, Day: today, PP: 4000}) // Alice overtakes everyone, the others weren't updated today
+
+	c, _ := testCollector(t, newFakeOsu(t), db)
+	if err := c.SnapshotLeaderboards(ctx, yesterday); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SnapshotLeaderboards(ctx, today); err != nil {
+		t.Fatal(err)
+	}
+
+	global, err := db.Leaderboard(ctx, today, 0, "", LeaderboardPP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ user, change int }{{4, 2}, {2, -1}, {3, -1}}
+	if len(global) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), global)
+	}
+	for i, w := range want {
+		e := global[i]
+		if e.Position != i+1 || e.UserID != w.user || e.Change == nil || *e.Change != w.change {
+			t.Errorf("position %d: expected user %d with change %d, got %+v", i+1, w.user, w.change, e)
+		}
+	}
+
+	country, err := db.Leaderboard(ctx, today, 0, "DE", LeaderboardPP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(country) != 2 || country[0].UserID != 4 || country[1].UserID != 3 {
+		t.Fatalf("expected Alice ahead of Bob in DE, got %+v", country)
+	}
+
+	// Snapshotting the same day again replaces it
+	if err := c.SnapshotLeaderboards(ctx, today); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := db.Leaderboard(ctx, today, 0, "", LeaderboardPP); len(again) != len(global) {
+		t.Fatalf("expected the snapshot to be replaced, got %+v", again)
+	}
+}
+
+func TestNormalizeMods(t *testing.T) {
+	if normalizeMods(nil) != nil {
+		t.Error("expected nil to match any mods")
+	}
+	if mods := normalizeMods([]string{"NM"}); mods == nil || len(mods) != 0 {
+		t.Errorf("expected NM to match no mods, got %v", mods)
+	}
+	if mods := normalizeMods([]string{"hd", " DT", "CL"}); len(mods) != 2 || mods[0] != "HD" || mods[1] != "DT" {
+		t.Errorf("expected HD and DT, got %v", mods)
+	}
+}
+
+func TestBeatmapLeaderboard(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	seedUser(t, db, 2, "peppy", "AU", false)
+	seedUser(t, db, 3, "Bob", "DE", false)
+	seedUser(t, db, 4, "Alice", "DE", false)
+	seedUser(t, db, 5, "Carol", "US", false)
+	seedUser(t, db, 6, "Restricted", "DE", true)
+
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, s := range []struct {
+		user   int
+		score  int
+		passed bool
+		mods   []string
+	}{
+		{2, 1000, true, []string{"CL"}},
+		{2, 900, true, nil},
+		{3, 1000, true, []string{"HD"}}, // ties with peppy but was set later
+		{4, 2000, false, nil},
+		{5, 800, true, []string{"DT", "HD"}},
+		{6, 5000, true, nil},
+	} {
+		if _, err := db.Pool().Exec(ctx, `
+		INSERT INTO scores (user_id, beatmap, score_id, score, accuracy, fc, mods, time, passed, mode)
+		VALUES ($1, 75, $2, $3, 100, false, $4, $5, $6, 0)`,
+			s.user, i+1, s.score, s.mods, start.Add(time.Duration(i)*time.Hour), s.passed,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	users := func(opts BeatmapLeaderboardOptions) []int {
+		t.Helper()
+		opts.Beatmap = 75
+		scores, err := db.BeatmapLeaderboard(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]int, len(scores))
+		for i, s := range scores {
+			if s.Position != opts.Offset+i+1 {
+				t.Errorf("expected position %d, got %d", opts.Offset+i+1, s.Position)
+			}
+			ids[i] = s.UserID
+		}
+		return ids
+	}
+
+	equal := func(name string, got []int, want ...int) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %v, got %v", name, want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%s: expected %v, got %v", name, want, got)
+			}
+		}
+	}
+
+	equal("passed", users(BeatmapLeaderboardOptions{}), 2, 3, 5)
+	equal("failed", users(BeatmapLeaderboardOptions{IncludeFailed: true}), 4, 2, 3, 5)
+	equal("nomod", users(BeatmapLeaderboardOptions{Mods: []string{"NM"}}), 2)
+	equal("hddt", users(BeatmapLeaderboardOptions{Mods: []string{"HD", "DT"}}), 5)
+	equal("page", users(BeatmapLeaderboardOptions{Limit: 1, Offset: 1}), 3)
+}
@@ -0,0 +1,280 @@
+package collector
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock is the advisory lock held while migrating, so two instances
+// starting at once don't apply the same migration twice.
+const migrationLock = 0x61647661 // "adva"
+
+// Migration is one versioned schema change, read from
+// migrations/<version>_<name>.up.sql and its .down.sql counterpart.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration and when it was applied, zero if it wasn't.
+type MigrationStatus struct {
+	Migration
+	AppliedAt time.Time
+}
+
+// Migrations returns every embedded migration ordered by version.
+func Migrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, file := range files {
+		base := strings.TrimPrefix(file, "migrations/")
+
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: missing .up.sql or .down.sql suffix", base)
+		}
+
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name>", base)
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", base, prefix)
+		}
+
+		content, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d: named both %q and %q", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d: needs both an up and a down file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrate applies every pending migration and returns how many were applied.
+func (p *Postgres) Migrate(ctx context.Context) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return p.MigrateTo(ctx, migrations[len(migrations)-1].Version)
+}
+
+// MigrateTo migrates up or down until version is the newest applied
+// migration. A version of 0 rolls back every migration. It returns how many
+// migrations were applied or rolled back. Each one runs in its own
+// transaction, so a failing migration leaves the schema at the previous one.
+func (p *Postgres) MigrateTo(ctx context.Context, version int) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	if version != 0 && !hasVersion(migrations, version) {
+		return 0, fmt.Errorf("unknown migration version %d", version)
+	}
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		return 0, err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version integer PRIMARY KEY,
+			name text NOT NULL,
+			applied_at timestamp with time zone DEFAULT now() NOT NULL
+		)`,
+	); err != nil {
+		return 0, err
+	}
+
+	applied, err := appliedMigrations(ctx, conn.Conn())
+	if err != nil {
+		return 0, err
+	}
+
+	log := slog.Default().With("component", "migrate")
+	count := 0
+
+	for _, m := range migrations {
+		if _, done := applied[m.Version]; done || m.Version > version {
+			continue
+		}
+
+		if err := runMigration(ctx, conn.Conn(), m.Up, `
+			INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+			m.Version, m.Name,
+		); err != nil {
+			return count, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+
+		log.Info("Applied migration", "version", m.Version, "name", m.Name)
+		count++
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, done := applied[m.Version]; !done || m.Version <= version {
+			continue
+		}
+
+		if err := runMigration(ctx, conn.Conn(), m.Down, `
+			DELETE FROM schema_migrations WHERE version = $1`,
+			m.Version,
+		); err != nil {
+			return count, fmt.Errorf("rollback %d_%s: %w", m.Version, m.Name, err)
+		}
+
+		log.Info("Rolled back migration", "version", m.Version, "name", m.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// ListMigrations reports every embedded migration and whether it was applied.
+func (p *Postgres) ListMigrations(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var exists bool
+	if err := conn.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time)
+	if exists {
+		if applied, err = appliedMigrations(ctx, conn.Conn()); err != nil {
+			return nil, err
+		}
+	}
+
+	status := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		status[i] = MigrationStatus{Migration: m, AppliedAt: applied[m.Version]}
+	}
+
+	return status, nil
+}
+
+func appliedMigrations(ctx context.Context, conn *pgx.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+
+	return applied, rows.Err()
+}
+
+// runMigration executes script and records it with the bookkeeping query in
+// a single transaction.
+func runMigration(ctx context.Context, conn *pgx.Conn, script string, record string, args ...any) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Without arguments the script is sent as a simple query, which allows
+	// several statements at once.
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func hasVersion(migrations []Migration, version int) bool {
+	for _, m := range migrations {
+		if m.Version == version {
+			return true
+		}
+	}
+	return false
+}
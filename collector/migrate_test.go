@@ -0,0 +1,62 @@
+package collector
+
+import "testing"
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatalf("expected migrations to start at 1, got %+v", migrations)
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("expected version %d, got %d (%s)", i+1, m.Version, m.Name)
+		}
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("migration %d is missing a direction", m.Version)
+		}
+	}
+}
+
+func TestMigrateRoundTrip(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := migrations[len(migrations)-1].Version
+
+	if n, err := db.Migrate(ctx); err != nil || n != 0 {
+		t.Fatalf("expected an up to date schema, ran %d: %v", n, err)
+	}
+
+	if _, err := db.MigrateTo(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := db.ListMigrations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range status {
+		if !m.AppliedAt.IsZero() {
+			t.Errorf("expected migration %d to be rolled back", m.Version)
+		}
+	}
+
+	// 0001 has to converge on databases created from the old dump, which
+	// already have the schema but no schema_migrations entry
+	if _, err := db.Pool().Exec(ctx, migrations[0].Up); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := db.Migrate(ctx); err != nil || n != latest {
+		t.Fatalf("expected %d migrations, ran %d: %v", latest, n, err)
+	}
+}
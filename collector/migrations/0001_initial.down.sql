@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS user_best;
+DROP TABLE IF EXISTS user_playcounts;
+DROP TABLE IF EXISTS scores;
+DROP TABLE IF EXISTS stats;
+DROP TABLE IF EXISTS stats_base;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS beatmaps;
+DROP FUNCTION IF EXISTS touch_user_last_update();
@@ -0,0 +1,159 @@
+-- The schema formerly kept as the advance.sql dump. Every statement is
+-- idempotent, so databases that were set up from an older dump converge on
+-- the same schema instead of failing.
+
+CREATE OR REPLACE FUNCTION touch_user_last_update() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    UPDATE users
+    SET last_update = NOW()
+    WHERE user_id = NEW.user_id;
+
+    RETURN NEW;
+END;
+$$;
+
+CREATE TABLE IF NOT EXISTS beatmaps (
+    id integer GENERATED BY DEFAULT AS IDENTITY CONSTRAINT beatmaps_pkey PRIMARY KEY,
+    beatmap_id integer NOT NULL,
+    beatmapset_id integer DEFAULT 0 NOT NULL,
+    title character varying(255) DEFAULT ''::character varying NOT NULL,
+    artist character varying(255) DEFAULT ''::character varying NOT NULL,
+    creator character varying(255) DEFAULT ''::character varying NOT NULL,
+    creator_id integer DEFAULT 0 NOT NULL,
+    version character varying(255) DEFAULT ''::character varying NOT NULL,
+    length integer DEFAULT 0 NOT NULL,
+    ranked integer NOT NULL,
+    last_update timestamp with time zone DEFAULT now() NOT NULL,
+    added timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS scores (
+    id integer GENERATED BY DEFAULT AS IDENTITY CONSTRAINT scores_go_pkey PRIMARY KEY,
+    user_id integer NOT NULL,
+    beatmap integer,
+    score_id bigint CONSTRAINT score_id_uni UNIQUE,
+    score integer,
+    accuracy real NOT NULL,
+    max_combo integer,
+    count_50 integer,
+    count_100 integer,
+    count_300 integer,
+    count_miss integer,
+    fc boolean NOT NULL,
+    mods text[],
+    "time" timestamp with time zone,
+    rank character varying(3),
+    passed boolean NOT NULL,
+    pp real DEFAULT 0,
+    mode smallint NOT NULL,
+    added timestamp with time zone DEFAULT now(),
+    source smallint
+);
+
+CREATE TABLE IF NOT EXISTS stats (
+    id integer GENERATED BY DEFAULT AS IDENTITY CONSTRAINT stats_go_pkey PRIMARY KEY,
+    user_id integer NOT NULL,
+    global integer DEFAULT 999999999,
+    country integer DEFAULT 999999999,
+    pp real DEFAULT 0,
+    accuracy real NOT NULL,
+    playcount integer NOT NULL,
+    playtime integer NOT NULL,
+    score bigint NOT NULL,
+    hits integer NOT NULL,
+    level integer NOT NULL,
+    progress integer NOT NULL,
+    mode smallint NOT NULL,
+    replays_watched integer DEFAULT 0 NOT NULL,
+    day date DEFAULT CURRENT_DATE NOT NULL,
+    imported boolean DEFAULT false NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS stats_base (
+    id integer GENERATED BY DEFAULT AS IDENTITY CONSTRAINT stats_base_pkey PRIMARY KEY,
+    user_id integer NOT NULL,
+    badges integer DEFAULT 0 NOT NULL,
+    banners integer DEFAULT 0 NOT NULL,
+    followers integer DEFAULT 0 NOT NULL,
+    achievements integer DEFAULT 0 NOT NULL,
+    day date DEFAULT CURRENT_DATE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_best (
+    id integer GENERATED BY DEFAULT AS IDENTITY CONSTRAINT user_best_pkey PRIMARY KEY,
+    user_id integer NOT NULL,
+    mode smallint NOT NULL,
+    "position" smallint NOT NULL,
+    score_id bigint NOT NULL CONSTRAINT user_best_score_id_fkey REFERENCES scores(score_id),
+    pp real DEFAULT 0 NOT NULL,
+    day date DEFAULT CURRENT_DATE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_playcounts (
+    id bigint GENERATED BY DEFAULT AS IDENTITY CONSTRAINT user_playcounts_pkey PRIMARY KEY,
+    user_id integer NOT NULL,
+    beatmap_id integer NOT NULL,
+    playcount integer NOT NULL,
+    delta integer DEFAULT 0 NOT NULL,
+    day date DEFAULT CURRENT_DATE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id integer GENERATED BY DEFAULT AS IDENTITY CONSTRAINT users_go_pkey PRIMARY KEY,
+    user_id integer NOT NULL CONSTRAINT user_id_uni UNIQUE,
+    username character varying(255) DEFAULT ''::character varying NOT NULL,
+    username_safe character varying(255) DEFAULT ''::character varying NOT NULL,
+    country character(2) DEFAULT ''::character varying NOT NULL,
+    restricted smallint DEFAULT 0 NOT NULL,
+    discord character varying(20) DEFAULT '0'::character varying NOT NULL,
+    last_update timestamp with time zone DEFAULT now() NOT NULL,
+    added timestamp with time zone DEFAULT now() NOT NULL,
+    last_playcounts timestamp with time zone
+);
+
+-- Columns that were added to the dump over time
+ALTER TABLE scores ADD COLUMN IF NOT EXISTS source smallint;
+ALTER TABLE stats ADD COLUMN IF NOT EXISTS imported boolean DEFAULT false NOT NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_playcounts timestamp with time zone;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'score_id_uni') THEN
+        ALTER TABLE scores ADD CONSTRAINT score_id_uni UNIQUE (score_id);
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'user_id_uni') THEN
+        ALTER TABLE users ADD CONSTRAINT user_id_uni UNIQUE (user_id);
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'user_best_score_id_fkey') THEN
+        ALTER TABLE user_best ADD CONSTRAINT user_best_score_id_fkey FOREIGN KEY (score_id) REFERENCES scores(score_id);
+    END IF;
+END;
+$$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS beatmaps_beatmap_id_idx ON beatmaps USING btree (beatmap_id);
+CREATE INDEX IF NOT EXISTS idx_beatmaps_beatmap_id ON beatmaps USING btree (beatmap_id);
+
+CREATE INDEX IF NOT EXISTS scores_go_user_id_mode_time_idx ON scores USING btree (user_id, mode, "time" DESC);
+
+CREATE INDEX IF NOT EXISTS idx_stats_mode ON stats USING btree (mode);
+CREATE INDEX IF NOT EXISTS idx_stats_mode_country ON stats USING btree (mode, country);
+CREATE INDEX IF NOT EXISTS idx_stats_mode_global ON stats USING btree (mode, global);
+CREATE INDEX IF NOT EXISTS idx_stats_mode_pp ON stats USING btree (mode, pp DESC);
+CREATE INDEX IF NOT EXISTS idx_stats_pp ON stats USING btree (pp DESC);
+CREATE INDEX IF NOT EXISTS idx_stats_user ON stats USING btree (user_id);
+CREATE INDEX IF NOT EXISTS idx_stats_user_mode ON stats USING btree (user_id, mode);
+CREATE UNIQUE INDEX IF NOT EXISTS stats_user_mode_day_idx ON stats USING btree (user_id, mode, day);
+
+CREATE UNIQUE INDEX IF NOT EXISTS stats_base_user_day_idx ON stats_base USING btree (user_id, day);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_best_user_mode_day_position_idx ON user_best USING btree (user_id, mode, day, "position");
+CREATE INDEX IF NOT EXISTS user_best_score_id_idx ON user_best USING btree (score_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_playcounts_user_beatmap_day_idx ON user_playcounts USING btree (user_id, beatmap_id, day DESC);
+CREATE INDEX IF NOT EXISTS user_playcounts_day_beatmap_idx ON user_playcounts USING btree (day, beatmap_id);
+
+CREATE OR REPLACE TRIGGER stats_touch_user AFTER INSERT OR UPDATE ON stats FOR EACH ROW EXECUTE FUNCTION touch_user_last_update();
@@ -0,0 +1,47 @@
+-- Copies the scores back into a single table. The bigint columns are kept,
+-- narrowing them again could fail on scores stored since.
+
+ALTER TABLE user_best DROP CONSTRAINT user_best_score_fkey;
+ALTER TABLE user_best DROP COLUMN score_time;
+
+ALTER TABLE scores RENAME TO scores_partitioned;
+
+DO $$
+BEGIN
+    EXECUTE format('ALTER SEQUENCE %s RENAME TO scores_partitioned_id_seq', pg_get_serial_sequence('scores_partitioned', 'id'));
+END;
+$$;
+
+CREATE TABLE scores (
+    id bigint GENERATED BY DEFAULT AS IDENTITY CONSTRAINT scores_go_pkey PRIMARY KEY,
+    user_id integer NOT NULL,
+    beatmap integer,
+    score_id bigint CONSTRAINT score_id_uni UNIQUE,
+    score bigint,
+    accuracy real NOT NULL,
+    max_combo integer,
+    count_50 integer,
+    count_100 integer,
+    count_300 integer,
+    count_miss integer,
+    fc boolean NOT NULL,
+    mods text[],
+    "time" timestamp with time zone,
+    rank character varying(3),
+    passed boolean NOT NULL,
+    pp real DEFAULT 0,
+    mode smallint NOT NULL,
+    added timestamp with time zone DEFAULT now(),
+    source smallint
+);
+
+INSERT INTO scores SELECT * FROM scores_partitioned ON CONFLICT (score_id) DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('scores', 'id'), COALESCE((SELECT max(id) FROM scores), 0) + 1, false);
+
+CREATE INDEX scores_go_user_id_mode_time_idx ON scores USING btree (user_id, mode, "time" DESC);
+
+DROP TABLE scores_partitioned;
+DROP FUNCTION create_scores_partition(timestamp with time zone);
+
+ALTER TABLE user_best ADD CONSTRAINT user_best_score_id_fkey FOREIGN KEY (score_id) REFERENCES scores(score_id) NOT VALID;
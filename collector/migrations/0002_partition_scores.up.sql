@@ -0,0 +1,121 @@
+-- Turns scores into a table partitioned by month on "time", with bigint ids
+-- and scores. Existing scores are copied over, which rewrites the whole table
+-- while holding a lock on it, so run this in a maintenance window on large
+-- databases.
+--
+-- Unique constraints on a partitioned table have to include "time", so score
+-- ids are only unique together with it. A score never changes its time, so
+-- this still prevents duplicates. user_best can't reference score_id on its
+-- own anymore, it keeps the time of the score and references both instead.
+
+ALTER TABLE user_best DROP CONSTRAINT IF EXISTS user_best_score_id_fkey;
+
+-- Takes the time the score is copied with below
+ALTER TABLE user_best ADD COLUMN score_time timestamp with time zone;
+
+UPDATE user_best b
+SET score_time = COALESCE(s."time", s.added, now())
+FROM scores s
+WHERE s.score_id = b.score_id;
+
+ALTER TABLE user_best ALTER COLUMN score_time SET NOT NULL;
+
+ALTER TABLE scores RENAME TO scores_legacy;
+ALTER TABLE scores_legacy RENAME CONSTRAINT scores_go_pkey TO scores_legacy_pkey;
+ALTER TABLE scores_legacy RENAME CONSTRAINT score_id_uni TO scores_legacy_score_id_key;
+ALTER INDEX scores_go_user_id_mode_time_idx RENAME TO scores_legacy_user_id_mode_time_idx;
+
+DO $$
+BEGIN
+    EXECUTE format('ALTER SEQUENCE %s RENAME TO scores_legacy_id_seq', pg_get_serial_sequence('scores_legacy', 'id'));
+END;
+$$;
+
+CREATE TABLE scores (
+    id bigint GENERATED BY DEFAULT AS IDENTITY,
+    user_id integer NOT NULL,
+    beatmap integer,
+    score_id bigint,
+    score bigint,
+    accuracy real NOT NULL,
+    max_combo integer,
+    count_50 integer,
+    count_100 integer,
+    count_300 integer,
+    count_miss integer,
+    fc boolean NOT NULL,
+    mods text[],
+    "time" timestamp with time zone NOT NULL,
+    rank character varying(3),
+    passed boolean NOT NULL,
+    pp real DEFAULT 0,
+    mode smallint NOT NULL,
+    added timestamp with time zone DEFAULT now(),
+    source smallint,
+    CONSTRAINT scores_pkey PRIMARY KEY (id, "time"),
+    CONSTRAINT scores_score_id_time_key UNIQUE (score_id, "time")
+) PARTITION BY RANGE ("time");
+
+CREATE INDEX scores_user_id_mode_time_idx ON scores USING btree (user_id, mode, "time" DESC);
+
+-- Catches scores outside of every monthly partition
+CREATE TABLE scores_default PARTITION OF scores DEFAULT;
+
+-- create_scores_partition creates the partition of the UTC month containing
+-- month and reports whether it had to. Scores of that month that ended up in
+-- the default partition are moved into it. The user_best rows referencing
+-- them are set aside while they are moved, their foreign key would keep the
+-- scores from leaving the default partition otherwise.
+CREATE FUNCTION create_scores_partition(month timestamp with time zone) RETURNS boolean
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    first timestamp := date_trunc('month', month AT TIME ZONE 'UTC');
+    starts_at timestamp with time zone := first AT TIME ZONE 'UTC';
+    ends_at timestamp with time zone := (first + interval '1 month') AT TIME ZONE 'UTC';
+    name text := 'scores_' || to_char(first, 'YYYY_MM');
+    best user_best[];
+BEGIN
+    IF to_regclass(name) IS NOT NULL THEN
+        RETURN false;
+    END IF;
+
+    SELECT array_agg(b) INTO best FROM user_best b WHERE b.score_time >= starts_at AND b.score_time < ends_at;
+    DELETE FROM user_best WHERE score_time >= starts_at AND score_time < ends_at;
+
+    EXECUTE format('CREATE TABLE %I (LIKE scores INCLUDING DEFAULTS)', name);
+    EXECUTE format(
+        'WITH moved AS (DELETE FROM scores_default WHERE "time" >= %L AND "time" < %L RETURNING *) INSERT INTO %I SELECT * FROM moved',
+        starts_at, ends_at, name
+    );
+    EXECUTE format('ALTER TABLE scores ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)', name, starts_at, ends_at);
+
+    INSERT INTO user_best SELECT * FROM unnest(best);
+
+    RETURN true;
+END;
+$$;
+
+SELECT create_scores_partition(month AT TIME ZONE 'UTC')
+FROM generate_series(
+    date_trunc('month', COALESCE((SELECT min("time") FROM scores_legacy), now()) AT TIME ZONE 'UTC'),
+    date_trunc('month', now() AT TIME ZONE 'UTC') + interval '3 months',
+    interval '1 month'
+) AS month;
+
+INSERT INTO scores (
+    id, user_id, beatmap, score_id, score, accuracy, max_combo,
+    count_50, count_100, count_300, count_miss, fc, mods, "time",
+    rank, passed, pp, mode, added, source
+)
+SELECT
+    id, user_id, beatmap, score_id, score, accuracy, max_combo,
+    count_50, count_100, count_300, count_miss, fc, mods, COALESCE("time", added, now()),
+    rank, passed, pp, mode, added, source
+FROM scores_legacy;
+
+SELECT setval(pg_get_serial_sequence('scores', 'id'), COALESCE((SELECT max(id) FROM scores), 0) + 1, false);
+
+DROP TABLE scores_legacy;
+
+ALTER TABLE user_best ADD CONSTRAINT user_best_score_fkey FOREIGN KEY (score_id, score_time) REFERENCES scores (score_id, "time");
@@ -0,0 +1,3 @@
+DROP TABLE leaderboards;
+
+ALTER TABLE stats DROP COLUMN ranked_score;
@@ -0,0 +1,20 @@
+-- stats.score is the total score, leaderboards rank by ranked score
+ALTER TABLE stats ADD COLUMN ranked_score bigint;
+
+-- Daily top lists per mode and kind, globally (country '') and per country.
+-- change is how many positions the user gained since the previous day, NULL
+-- if they weren't on that list.
+CREATE TABLE leaderboards (
+    day date NOT NULL,
+    mode smallint NOT NULL,
+    country character varying(2) NOT NULL,
+    kind character varying(16) NOT NULL,
+    "position" integer NOT NULL,
+    user_id integer NOT NULL,
+    value double precision NOT NULL,
+    change integer,
+    CONSTRAINT leaderboards_pkey PRIMARY KEY (day, mode, country, kind, "position")
+);
+
+CREATE UNIQUE INDEX leaderboards_day_mode_country_kind_user_idx ON leaderboards USING btree (day, mode, country, kind, user_id);
+CREATE INDEX leaderboards_user_day_idx ON leaderboards USING btree (user_id, day DESC);
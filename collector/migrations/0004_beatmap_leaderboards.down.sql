@@ -0,0 +1 @@
+DROP INDEX scores_beatmap_mode_score_idx;
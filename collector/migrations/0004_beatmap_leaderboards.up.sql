@@ -0,0 +1,3 @@
+-- Backs beatmap leaderboards, which read the best scores of a beatmap in a
+-- mode. Building it locks every scores partition against writes.
+CREATE INDEX scores_beatmap_mode_score_idx ON scores USING btree (beatmap, mode, score DESC, "time");
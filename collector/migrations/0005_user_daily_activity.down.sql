@@ -0,0 +1,3 @@
+DROP INDEX stats_day_idx;
+DROP TABLE aggregation_state;
+DROP TABLE user_daily_activity;
@@ -0,0 +1,24 @@
+-- Daily totals per player, maintained from new scores and stats. pp_gained
+-- and playtime are the differences to the previous stats of the player, so
+-- days in between that weren't updated are counted on the next update.
+CREATE TABLE user_daily_activity (
+    user_id integer NOT NULL,
+    mode smallint NOT NULL,
+    day date NOT NULL,
+    plays integer DEFAULT 0 NOT NULL,
+    passes integer DEFAULT 0 NOT NULL,
+    total_score bigint DEFAULT 0 NOT NULL,
+    pp_gained real DEFAULT 0 NOT NULL,
+    playtime integer DEFAULT 0 NOT NULL,
+    distinct_beatmaps integer DEFAULT 0 NOT NULL,
+    CONSTRAINT user_daily_activity_pkey PRIMARY KEY (user_id, mode, day)
+);
+
+-- How far the derived tables got, e.g. the last aggregated score id
+CREATE TABLE aggregation_state (
+    name text PRIMARY KEY,
+    last_id bigint DEFAULT 0 NOT NULL,
+    last_day date
+);
+
+CREATE INDEX stats_day_idx ON stats USING btree (day);
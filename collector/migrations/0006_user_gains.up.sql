@@ -0,0 +1,18 @@
+-- Gains of every player over a day, week (starting monday) or month, from
+-- their latest stats before the period to their latest stats within it. A
+-- positive rank gain means the player climbed, it is NULL if they were
+-- unranked at either end. country is the one at the time of computing, so
+-- top gainers per country don't need to join users.
+CREATE TABLE user_gains (
+    period character varying(5) NOT NULL,
+    start date NOT NULL,
+    mode smallint NOT NULL,
+    user_id integer NOT NULL,
+    country character varying(2) NOT NULL,
+    pp real NOT NULL,
+    rank integer,
+    playcount integer NOT NULL,
+    CONSTRAINT user_gains_pkey PRIMARY KEY (period, start, mode, user_id)
+);
+
+CREATE INDEX user_gains_period_start_mode_country_idx ON user_gains USING btree (period, start, mode, country);
@@ -0,0 +1 @@
+DROP TABLE milestones_sent;
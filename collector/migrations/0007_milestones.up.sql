@@ -0,0 +1,11 @@
+-- Milestones that were notified about, so every one is only sent once per
+-- rule. key is the threshold for rank and pp milestones, the day for country
+-- firsts and the score id for score milestones.
+CREATE TABLE milestones_sent (
+    rule character varying(64) NOT NULL,
+    user_id integer NOT NULL,
+    mode smallint NOT NULL,
+    key character varying(32) NOT NULL,
+    sent_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT milestones_sent_pkey PRIMARY KEY (rule, user_id, mode, key)
+);
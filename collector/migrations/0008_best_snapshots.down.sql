@@ -0,0 +1 @@
+DROP TABLE best_snapshots;
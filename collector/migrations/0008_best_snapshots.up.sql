@@ -0,0 +1,14 @@
+-- When the best scores of a player were last snapshotted in a mode, whether
+-- they had any or not. Players without best scores never get user_best rows,
+-- so those can't tell if a snapshot was attempted.
+CREATE TABLE best_snapshots (
+    user_id integer NOT NULL,
+    mode smallint NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT best_snapshots_pkey PRIMARY KEY (user_id, mode)
+);
+
+INSERT INTO best_snapshots (user_id, mode, updated_at)
+SELECT user_id, mode, MAX(day)
+FROM user_best
+GROUP BY user_id, mode;
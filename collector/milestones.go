@@ -0,0 +1,357 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	discordwebhook "github.com/bensch777/discord-webhook-golang"
+	"github.com/calemy/advance-go/osuapi"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Events a milestone rule can notify about
+const (
+	MilestoneCountryFirst = "country_first" // a player became #1 in their country
+	MilestoneGlobalRank   = "global_rank"   // a player entered the global top of a threshold
+	MilestonePP           = "pp"            // a player reached a pp threshold
+	MilestoneTopPlay      = "top_play"      // a score worth more pp than the player's #1
+	MilestoneSS           = "ss"            // an SS on a beatmap of at least the threshold in stars
+)
+
+// MilestoneRule sends the events of one kind to a Discord webhook. global_rank
+// and pp need thresholds, ss needs exactly one star rating. For top_play an
+// optional threshold is the least pp the score has to be worth.
+type MilestoneRule struct {
+	Name       string    `json:"name"`
+	Event      string    `json:"event"`
+	Thresholds []float64 `json:"thresholds,omitempty"`
+	Modes      []int     `json:"modes,omitempty"` // any mode if empty
+	Webhook    string    `json:"webhook"`
+}
+
+func (r MilestoneRule) Validate() error {
+	if !ruleName.MatchString(r.Name) {
+		return fmt.Errorf("milestone rule %q: the name may only contain a-z, 0-9, _ and -", r.Name)
+	}
+	if r.Webhook == "" {
+		return fmt.Errorf("milestone rule %q: missing webhook", r.Name)
+	}
+
+	switch r.Event {
+	case MilestoneCountryFirst:
+		if len(r.Thresholds) > 0 {
+			return fmt.Errorf("milestone rule %q: %s doesn't take thresholds", r.Name, r.Event)
+		}
+	case MilestoneGlobalRank, MilestonePP:
+		if len(r.Thresholds) == 0 {
+			return fmt.Errorf("milestone rule %q: %s needs thresholds", r.Name, r.Event)
+		}
+	case MilestoneTopPlay:
+		if len(r.Thresholds) > 1 {
+			return fmt.Errorf("milestone rule %q: %s takes at most one threshold", r.Name, r.Event)
+		}
+	case MilestoneSS:
+		if len(r.Thresholds) != 1 {
+			return fmt.Errorf("milestone rule %q: %s needs exactly one threshold", r.Name, r.Event)
+		}
+	default:
+		return fmt.Errorf("milestone rule %q: unknown event %q", r.Name, r.Event)
+	}
+
+	for _, threshold := range r.Thresholds {
+		if threshold <= 0 {
+			return fmt.Errorf("milestone rule %q: thresholds have to be positive", r.Name)
+		}
+	}
+	for _, mode := range r.Modes {
+		if mode < 0 || mode > 3 {
+			return fmt.Errorf("milestone rule %q: invalid mode %d", r.Name, mode)
+		}
+	}
+	return nil
+}
+
+func (r MilestoneRule) appliesTo(mode int) bool {
+	return len(r.Modes) == 0 || slices.Contains(r.Modes, mode)
+}
+
+// LoadMilestoneRules reads a JSON list of rules. A missing file means no
+// rules.
+func LoadMilestoneRules(path string) ([]MilestoneRule, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []MilestoneRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	names := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		if _, exists := names[rule.Name]; exists {
+			return nil, fmt.Errorf("milestone rule %q is defined twice", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+	}
+
+	return rules, nil
+}
+
+// hasMilestones reports whether any rule listens to one of the events, so
+// their inputs are only queried when needed.
+func (c *Collector) hasMilestones(events ...string) bool {
+	for _, rule := range c.Config.MilestoneRules {
+		if slices.Contains(events, rule.Event) {
+			return true
+		}
+	}
+	return false
+}
+
+// userMilestones compares the stats of a player before and after an update.
+// Players seen for the first time have nothing to compare against and never
+// notify.
+func (c *Collector) userMilestones(ctx context.Context, user *osuapi.UserExtended, mode int, before *osuapi.UserStatistics) {
+	after := user.Statistics
+	if before == nil || after == nil {
+		return
+	}
+
+	for _, rule := range c.Config.MilestoneRules {
+		if !rule.appliesTo(mode) {
+			continue
+		}
+
+		switch rule.Event {
+		case MilestoneCountryFirst:
+			if rankOf(after.CountryRank) != 1 || rankOf(before.CountryRank) == 1 {
+				continue
+			}
+			// Once a day, so a player trading #1 back and forth doesn't flood
+			// the channel.
+			day := time.Now().UTC().Format(time.DateOnly)
+			c.notifyMilestone(ctx, rule, user.ID, mode, day, userEmbed(user, mode,
+				fmt.Sprintf("%s is now #1 in %s!", user.Username, user.CountryCode),
+			))
+
+		case MilestoneGlobalRank:
+			// Only the best threshold crossed notifies, a jump from #1500 to #50
+			// doesn't need to mention the top 1000 as well.
+			crossed := 0.0
+			for _, threshold := range rule.Thresholds {
+				if float64(rankOf(after.GlobalRank)) <= threshold && float64(rankOf(before.GlobalRank)) > threshold {
+					if crossed == 0 || threshold < crossed {
+						crossed = threshold
+					}
+				}
+			}
+			if crossed == 0 {
+				continue
+			}
+			c.notifyMilestone(ctx, rule, user.ID, mode, strconv.FormatFloat(crossed, 'f', -1, 64), userEmbed(user, mode,
+				fmt.Sprintf("%s reached the global top %s!", user.Username, formatThreshold(crossed)),
+			))
+
+		case MilestonePP:
+			crossed := 0.0
+			for _, threshold := range rule.Thresholds {
+				if after.PP >= threshold && before.PP < threshold && threshold > crossed {
+					crossed = threshold
+				}
+			}
+			if crossed == 0 {
+				continue
+			}
+			c.notifyMilestone(ctx, rule, user.ID, mode, strconv.FormatFloat(crossed, 'f', -1, 64), userEmbed(user, mode,
+				fmt.Sprintf("%s reached %spp!", user.Username, formatThreshold(crossed)),
+			))
+		}
+	}
+}
+
+// scoreMilestones checks a freshly stored score. Scores from best and firsts
+// snapshots or backfills aren't new, so only the feed and recent scores count.
+func (c *Collector) scoreMilestones(ctx context.Context, s *osuapi.Score, source uint8) {
+	if source != SourceFeed && source != SourceRecent {
+		return
+	}
+	if !s.Passed {
+		return
+	}
+
+	top := -1.0
+
+	for _, rule := range c.Config.MilestoneRules {
+		if !rule.appliesTo(s.RulesetID) {
+			continue
+		}
+
+		switch rule.Event {
+		case MilestoneTopPlay:
+			if s.PP <= 0 || (len(rule.Thresholds) > 0 && s.PP < rule.Thresholds[0]) {
+				continue
+			}
+
+			if top < 0 {
+				var err error
+				if top, err = c.Store.TopPlay(ctx, s.UserID, s.RulesetID, s.ID); err != nil {
+					c.Logger.Error("Couldn't look up the top play", "user_id", s.UserID, "mode", s.RulesetID, "error", err)
+					return
+				}
+			}
+
+			// Without any other score there is no top play to beat yet
+			if top == 0 || s.PP <= top {
+				continue
+			}
+			c.notifyMilestone(ctx, rule, s.UserID, s.RulesetID, strconv.Itoa(s.ID), scoreEmbed(s,
+				fmt.Sprintf("New top play: %.2fpp (previously %.2fpp)", s.PP, top),
+			))
+
+		case MilestoneSS:
+			if (s.Rank != "X" && s.Rank != "XH") || s.Beatmap == nil || s.Beatmap.DifficultyRating < rule.Thresholds[0] {
+				continue
+			}
+			c.notifyMilestone(ctx, rule, s.UserID, s.RulesetID, strconv.Itoa(s.ID), scoreEmbed(s,
+				fmt.Sprintf("SS on a %.2f★ beatmap!", s.Beatmap.DifficultyRating),
+			))
+		}
+	}
+}
+
+// notifyMilestone sends the embed to the webhook of the rule, unless the
+// player already got a notification with the same key from it.
+func (c *Collector) notifyMilestone(ctx context.Context, rule MilestoneRule, id int, mode int, key string, embed discordwebhook.Embed) {
+	log := c.Logger.With("rule", rule.Name, "user_id", id, "mode", mode, "key", key)
+
+	notifier, exists := c.Milestones[rule.Webhook]
+	if !exists {
+		log.Warn("No webhook registered for milestone rule")
+		return
+	}
+
+	fresh, err := c.Store.MarkMilestone(ctx, rule.Name, id, mode, key)
+	if err != nil {
+		log.Error("Couldn't record milestone", "error", err)
+		return
+	}
+	if !fresh {
+		return
+	}
+
+	log.Info("Reached milestone")
+
+	notifier.Queue(discordwebhook.Hook{
+		Username:   "Advance",
+		Avatar_url: "https://a.ppy.sh/9527931",
+		Embeds:     []discordwebhook.Embed{embed},
+	})
+}
+
+func userEmbed(user *osuapi.UserExtended, mode int, title string) discordwebhook.Embed {
+	p := message.NewPrinter(language.English)
+
+	return discordwebhook.Embed{
+		Title:     title,
+		Url:       fmt.Sprintf("https://osu.ppy.sh/users/%d/%s", user.ID, osuapi.ModeStr(mode)),
+		Color:     0xF5C542,
+		Timestamp: time.Now(),
+		Thumbnail: discordwebhook.Thumbnail{
+			Url: fmt.Sprintf("https://a.ppy.sh/%d", user.ID),
+		},
+		Fields: []discordwebhook.Field{
+			{
+				Name:   "Country Rank",
+				Value:  p.Sprintf("%d", rankOf(user.Statistics.CountryRank)),
+				Inline: true,
+			},
+			{
+				Name:   "Global Rank",
+				Value:  p.Sprintf("%d", rankOf(user.Statistics.GlobalRank)),
+				Inline: true,
+			},
+			{
+				Name:   "PP",
+				Value:  fmt.Sprintf("%.2f", user.Statistics.PP),
+				Inline: true,
+			},
+		},
+	}
+}
+
+func scoreEmbed(s *osuapi.Score, title string) discordwebhook.Embed {
+	beatmap := fmt.Sprintf("%d", s.BeatmapID)
+	if s.Beatmapset != nil && s.Beatmap != nil {
+		beatmap = fmt.Sprintf("%s - %s [%s]", s.Beatmapset.Artist, s.Beatmapset.Title, s.Beatmap.Version)
+	}
+
+	mods := "NM"
+	if len(s.Mods) > 0 {
+		mods = ""
+		for _, mod := range s.Mods {
+			mods += mod.Acronym
+		}
+	}
+
+	return discordwebhook.Embed{
+		Title:     title,
+		Url:       fmt.Sprintf("https://osu.ppy.sh/scores/%d", s.ID),
+		Color:     0xF5C542,
+		Timestamp: s.EndedAt,
+		Thumbnail: discordwebhook.Thumbnail{
+			Url: fmt.Sprintf("https://a.ppy.sh/%d", s.UserID),
+		},
+		Fields: []discordwebhook.Field{
+			{
+				Name:  "Player",
+				Value: fmt.Sprintf("https://osu.ppy.sh/users/%d", s.UserID),
+			},
+			{
+				Name:  "Beatmap",
+				Value: beatmap,
+			},
+			{
+				Name:   "Mods",
+				Value:  mods,
+				Inline: true,
+			},
+			{
+				Name:   "Accuracy",
+				Value:  fmt.Sprintf("%.2f%%", s.Accuracy*100),
+				Inline: true,
+			},
+			{
+				Name:   "PP",
+				Value:  fmt.Sprintf("%.2f", s.PP),
+				Inline: true,
+			},
+		},
+	}
+}
+
+// rankOf treats unranked players as ranked below everyone.
+func rankOf(rank *int) int {
+	if rank == nil || *rank <= 0 {
+		return 999999999
+	}
+	return *rank
+}
+
+func formatThreshold(threshold float64) string {
+	return message.NewPrinter(language.English).Sprintf("%v", threshold)
+}
@@ -0,0 +1,187 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+func TestLoadMilestoneRules(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(content string) string {
+		path := filepath.Join(dir, "milestones.json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rules, err := LoadMilestoneRules(filepath.Join(dir, "missing.json"))
+	if err != nil || rules != nil {
+		t.Fatalf("expected no rules without a file, got %v: %v", rules, err)
+	}
+
+	rules, err = LoadMilestoneRules(write(`[{"name": "top", "event": "global_rank", "thresholds": [1000, 100], "modes": [0], "webhook": "https://hook"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || len(rules[0].Thresholds) != 2 || rules[0].Webhook != "https://hook" {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+
+	invalid := []string{
+		`[{"name": "Top", "event": "global_rank", "thresholds": [100], "webhook": "https://hook"}]`,
+		`[{"name": "top", "event": "global_rank", "thresholds": [100]}]`,
+		`[{"name": "top", "event": "rank", "thresholds": [100], "webhook": "https://hook"}]`,
+		`[{"name": "top", "event": "global_rank", "webhook": "https://hook"}]`,
+		`[{"name": "top", "event": "global_rank", "thresholds": [-1], "webhook": "https://hook"}]`,
+		`[{"name": "first", "event": "country_first", "thresholds": [1], "webhook": "https://hook"}]`,
+		`[{"name": "ss", "event": "ss", "webhook": "https://hook"}]`,
+		`[{"name": "top", "event": "pp", "thresholds": [1000], "modes": [4], "webhook": "https://hook"}]`,
+		`[{"name": "top", "event": "pp", "thresholds": [1000], "webhook": "https://hook"}, {"name": "top", "event": "ss", "thresholds": [7], "webhook": "https://hook"}]`,
+	}
+	for _, content := range invalid {
+		if _, err := LoadMilestoneRules(write(content)); err == nil {
+			t.Errorf("expected %s to be rejected", content)
+		}
+	}
+}
+
+func TestUserMilestones(t *testing.T) {
+	f := newFakeOsu(t)
+	store := newMemStore()
+	c, _ := testCollector(t, f, store)
+
+	f.Load(t, "/users/2", "user.json")
+	f.Load(t, "/users/2/scores/recent", "user_recent.json")
+
+	ranks, plays := &recorder{}, &recorder{}
+	c.Milestones = map[string]Notifier{"https://ranks": ranks, "https://plays": plays}
+	c.Config.MilestoneRules = []MilestoneRule{
+		{Name: "global", Event: MilestoneGlobalRank, Thresholds: []float64{200000, 120000, 1000}, Webhook: "https://ranks"},
+		{Name: "pp", Event: MilestonePP, Thresholds: []float64{1000, 1500, 2000}, Webhook: "https://ranks"},
+		{Name: "first", Event: MilestoneCountryFirst, Webhook: "https://ranks"},
+		{Name: "taiko", Event: MilestonePP, Thresholds: []float64{1500}, Modes: []int{1}, Webhook: "https://ranks"},
+		{Name: "top", Event: MilestoneTopPlay, Webhook: "https://plays"},
+	}
+
+	store.top[UserMode{2, 0}] = 100
+
+	// Without previous stats there is nothing to compare with
+	if err := c.UpdateUser(t.Context(), 2, 1<<osuapi.ModeStd); err != nil {
+		t.Fatal(err)
+	}
+	if ranks.Len() != 0 {
+		t.Fatalf("expected no milestones on the first update, got %d", ranks.Len())
+	}
+
+	// The recent score is worth 140.2pp, more than the top play of 100pp
+	if plays.Len() != 1 {
+		t.Fatalf("expected a top play, got %d", plays.Len())
+	}
+
+	rank := 150000
+	store.latest[UserMode{2, 0}] = &osuapi.UserStatistics{GlobalRank: &rank, PP: 1400}
+	if err := c.UpdateUser(t.Context(), 2, 1<<osuapi.ModeStd); err != nil {
+		t.Fatal(err)
+	}
+
+	// 150000 -> 119990 only crosses 120000, 1400 -> 1523.4pp only 1500
+	if ranks.Len() != 2 {
+		t.Fatalf("expected a rank and a pp milestone, got %d", ranks.Len())
+	}
+	titles := map[string]bool{}
+	for _, hook := range ranks.hooks {
+		titles[hook.Embeds[0].Title] = true
+	}
+	if !titles["peppy reached the global top 120,000!"] || !titles["peppy reached 1,500pp!"] {
+		t.Fatalf("unexpected milestones %v", titles)
+	}
+
+	// Crossing the same thresholds again doesn't notify twice
+	store.latest[UserMode{2, 0}] = &osuapi.UserStatistics{GlobalRank: &rank, PP: 1400}
+
+	if err := c.UpdateUser(t.Context(), 2, 1<<osuapi.ModeStd); err != nil {
+		t.Fatal(err)
+	}
+	if ranks.Len() != 2 || plays.Len() != 1 {
+		t.Fatalf("expected milestones to be sent once, got %d and %d", ranks.Len(), plays.Len())
+	}
+}
+
+func TestScoreMilestones(t *testing.T) {
+	f := newFakeOsu(t)
+	store := newMemStore()
+	c, _ := testCollector(t, f, store)
+
+	hooks := &recorder{}
+	c.Milestones = map[string]Notifier{"https://hook": hooks}
+	c.Config.MilestoneRules = []MilestoneRule{
+		{Name: "ss", Event: MilestoneSS, Thresholds: []float64{6}, Webhook: "https://hook"},
+		{Name: "top", Event: MilestoneTopPlay, Thresholds: []float64{300}, Webhook: "https://hook"},
+	}
+
+	store.top[UserMode{2, 0}] = 250
+
+	tests := []struct {
+		name   string
+		score  osuapi.Score
+		source uint8
+		sent   int
+	}{
+		{"ss", osuapi.Score{ID: 1, UserID: 2, Passed: true, Rank: "XH", Beatmap: &osuapi.BeatmapExtended{DifficultyRating: 6.5}}, SourceFeed, 1},
+		{"ss on an easy map", osuapi.Score{ID: 2, UserID: 2, Passed: true, Rank: "X", Beatmap: &osuapi.BeatmapExtended{DifficultyRating: 5.9}}, SourceFeed, 0},
+		{"top play", osuapi.Score{ID: 3, UserID: 2, Passed: true, Rank: "A", PP: 320}, SourceRecent, 1},
+		{"top play below the threshold", osuapi.Score{ID: 4, UserID: 2, Passed: true, Rank: "A", PP: 260}, SourceFeed, 0},
+		{"failed", osuapi.Score{ID: 5, UserID: 2, Rank: "F", PP: 320}, SourceFeed, 0},
+		{"best snapshot", osuapi.Score{ID: 6, UserID: 2, Passed: true, Rank: "A", PP: 320}, SourceBest, 0},
+		{"first score", osuapi.Score{ID: 7, UserID: 3, Passed: true, Rank: "A", PP: 320}, SourceFeed, 0},
+		{"ss and top play", osuapi.Score{ID: 8, UserID: 2, Passed: true, Rank: "X", PP: 400, Beatmap: &osuapi.BeatmapExtended{DifficultyRating: 7}}, SourceFeed, 2},
+		{"same score again", osuapi.Score{ID: 8, UserID: 2, Passed: true, Rank: "X", PP: 400, Beatmap: &osuapi.BeatmapExtended{DifficultyRating: 7}}, SourceRecent, 0},
+	}
+
+	for _, tt := range tests {
+		before := hooks.Len()
+		c.scoreMilestones(t.Context(), &tt.score, tt.source)
+		if sent := hooks.Len() - before; sent != tt.sent {
+			t.Errorf("%s: expected %d notifications, got %d", tt.name, tt.sent, sent)
+		}
+	}
+}
+
+func TestTopPlay(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	at := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, pp := range []float64{100, 300, 400} {
+		s := osuapi.Score{ID: i + 1, UserID: 2, EndedAt: at, Passed: true, PP: pp}
+		if err := db.InsertScore(ctx, &s, SourceBest); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Score 1 was worth 600 before losing pp, which only the older snapshot
+	// still shows
+	if err := db.SnapshotBest(ctx, 2, 0, []osuapi.Score{{ID: 1, EndedAt: at, PP: 600}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Pool().Exec(ctx, `UPDATE user_best SET day = CURRENT_DATE - 1`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SnapshotBest(ctx, 2, 0, []osuapi.Score{{ID: 2, EndedAt: at, PP: 300}, {ID: 1, EndedAt: at, PP: 100}}); err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := db.TopPlay(ctx, 2, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top != 300 {
+		t.Fatalf("expected the newest snapshot to count, got %v", top)
+	}
+}
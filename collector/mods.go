@@ -1,10 +1,10 @@
-package main
+package collector
 
-type Mod struct { //We do not support modifiers. I don't care. - v3 (Nanoo)
-	Acronym string `json:"acronym"`
-}
+import (
+	"github.com/calemy/advance-go/osuapi"
+)
 
-func convertMods(mods []Mod) string {
+func convertMods(mods []osuapi.Mod) string {
 	if len(mods) == 0 {
 		return "{}"
 	}
@@ -0,0 +1,39 @@
+package collector
+
+import (
+	"context"
+	"time"
+)
+
+// CreatePartitions creates the scores partitions of the current month and the
+// PartitionMonths after it that don't exist yet.
+func (c *Collector) CreatePartitions(ctx context.Context) error {
+	created, err := c.Store.CreatePartitions(ctx, time.Now().UTC(), c.Config.PartitionMonths)
+	if err != nil {
+		return err
+	}
+
+	if created > 0 {
+		c.Logger.Info("Created score partitions", "count", created)
+	}
+	return nil
+}
+
+// schedulePartitions keeps creating partitions ahead of time, so scores never
+// end up in the default partition.
+func (c *Collector) schedulePartitions(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.CreatePartitions(ctx); err != nil {
+			c.Logger.Error("Couldn't create score partitions", "error", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+func TestCreatePartitions(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	month := time.Date(2099, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// t.Context is already cancelled when cleanups run
+	t.Cleanup(func() {
+		db.Pool().Exec(context.Background(), `DELETE FROM user_best; DROP TABLE IF EXISTS scores_2099_01, scores_2099_02`)
+	})
+
+	// Without a partition the score ends up in the default one
+	if _, err := db.Pool().Exec(ctx, `
+	INSERT INTO scores (user_id, score_id, accuracy, fc, time, passed, mode)
+	VALUES (2, 1, 100, true, $1, true, 0)`,
+		month.Add(12*time.Hour),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// Best snapshots reference the score wherever it is
+	if err := db.SnapshotBest(ctx, 2, 0, []osuapi.Score{{ID: 1, EndedAt: month.Add(12 * time.Hour)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SnapshotBest(ctx, 3, 0, []osuapi.Score{{ID: 2, EndedAt: month}}); err == nil {
+		t.Fatal("expected a snapshot of a score that isn't stored to be rejected")
+	}
+
+	created, err := db.CreatePartitions(ctx, month, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 2 {
+		t.Fatalf("expected 2 partitions, created %d", created)
+	}
+
+	if created, err := db.CreatePartitions(ctx, month, 1); err != nil || created != 0 {
+		t.Fatalf("expected existing partitions to be kept, created %d: %v", created, err)
+	}
+
+	var partition string
+	if err := db.Pool().QueryRow(ctx, `SELECT tableoid::regclass::text FROM scores WHERE score_id = 1`).Scan(&partition); err != nil {
+		t.Fatal(err)
+	}
+	if partition != "scores_2099_01" {
+		t.Fatalf("expected the score to move to scores_2099_01, found it in %s", partition)
+	}
+
+	var snapshotted int
+	if err := db.Pool().QueryRow(ctx, `
+	SELECT COUNT(*) FROM user_best b JOIN scores s ON s.score_id = b.score_id AND s.time = b.score_time`,
+	).Scan(&snapshotted); err != nil {
+		t.Fatal(err)
+	}
+	if snapshotted != 1 {
+		t.Fatalf("expected the snapshot to follow the moved score, got %d", snapshotted)
+	}
+}
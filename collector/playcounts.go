@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+// AllMostPlayed pages through every most played beatmap of the user. Each
+// page waits on budget so the job never eats into the score and user updates.
+func (c *Collector) AllMostPlayed(ctx context.Context, id int, budget *rate.Limiter) ([]osuapi.BeatmapPlaycount, error) {
+	counts := make([]osuapi.BeatmapPlaycount, 0, 100)
+
+	for offset := 0; ; offset += 100 {
+		if err := budget.Wait(ctx); err != nil {
+			return counts, err
+		}
+
+		page, err := c.API.GetMostPlayed(ctx, id, 100, offset)
+		if err != nil {
+			return counts, err
+		}
+
+		counts = append(counts, page...)
+
+		if len(page) < 100 {
+			return counts, nil
+		}
+	}
+}
+
+// collectPlaycounts updates the playcounts of every user that played since
+// their last run, but at most once every given amount of days.
+func (c *Collector) collectPlaycounts(ctx context.Context, days int, budget *rate.Limiter) {
+	ids, err := c.Store.PendingPlaycounts(ctx, days)
+	if err != nil {
+		c.Logger.Error("Couldn't load users for playcounts", "error", err)
+		return
+	}
+
+	c.Logger.Info("Collecting playcounts", "users", len(ids))
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+
+		counts, err := c.AllMostPlayed(ctx, id, budget)
+		if err != nil {
+			if !errors.Is(err, osuapi.ErrNotFound) {
+				c.Logger.Error("Couldn't fetch playcounts", "user_id", id, "error", err)
+			}
+			continue
+		}
+
+		if err := c.Store.UpdatePlaycounts(ctx, id, counts); err != nil {
+			c.Logger.Error("Couldn't store playcounts", "user_id", id, "error", err)
+		}
+	}
+}
+
+func (c *Collector) schedulePlaycounts(ctx context.Context, days int, perMinute int) {
+	budget := rate.NewLimiter(rate.Every(time.Minute/time.Duration(perMinute)), 1)
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		c.collectPlaycounts(ctx, days, budget)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
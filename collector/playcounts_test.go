@@ -0,0 +1,119 @@
+package collector
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+func TestCollectPlaycounts(t *testing.T) {
+	f := newFakeOsu(t)
+	store := newMemStore()
+	c, _ := testCollector(t, f, store)
+
+	for _, id := range []int{2, 3, 4} {
+		store.CreateUser(t.Context(), &osuapi.UserExtended{ID: id})
+	}
+	store.RestrictUser(t.Context(), 4)
+
+	// More than fit on a single page
+	counts := make([]osuapi.BeatmapPlaycount, 150)
+	for i := range counts {
+		counts[i] = osuapi.BeatmapPlaycount{BeatmapID: i + 1, Count: i + 1}
+	}
+	f.Set(t, "/users/2/beatmapsets/most_played", counts)
+	f.Status("/users/3/beatmapsets/most_played", http.StatusNotFound)
+
+	budget := rate.NewLimiter(rate.Inf, 1)
+	c.collectPlaycounts(t.Context(), 7, budget)
+
+	if got := store.playcounts[2]; len(got) != 150 || got[149].Count != 150 {
+		t.Fatalf("expected all 150 playcounts of player 2, got %d", len(got))
+	}
+	if hits := f.Hits("/users/2/beatmapsets/most_played"); hits != 2 {
+		t.Fatalf("expected 2 pages, got %d", hits)
+	}
+	if _, exists := store.playcounts[3]; exists {
+		t.Fatal("expected nothing to be stored for a player that wasn't found")
+	}
+	if hits := f.Hits("/users/4/beatmapsets/most_played"); hits != 0 {
+		t.Fatal("expected restricted players to be skipped")
+	}
+
+	// Only the player that failed is pending again
+	c.collectPlaycounts(t.Context(), 7, budget)
+
+	if f.Hits("/users/2/beatmapsets/most_played") != 2 || f.Hits("/users/3/beatmapsets/most_played") != 2 {
+		t.Fatal("expected only player 3 to be fetched again")
+	}
+}
+
+func TestPendingPlaycounts(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	for _, id := range []int{2, 3, 4, 5, 6} {
+		seedUser(t, db, id, "", "DE", id == 6)
+	}
+
+	// 2 was never collected, 3 just now, 4 a while ago and played since,
+	// 5 a while ago without playing since and 6 is restricted
+	earlier := time.Now().AddDate(0, 0, -10)
+	for _, u := range []struct {
+		id         int
+		update     time.Time
+		playcounts time.Time
+	}{
+		{3, time.Now(), time.Now()},
+		{4, time.Now(), earlier},
+		{5, earlier.Add(-time.Hour), earlier},
+	} {
+		if _, err := db.Pool().Exec(ctx, `
+		UPDATE users SET last_update = $2, last_playcounts = $3 WHERE user_id = $1`,
+			u.id, u.update, u.playcounts,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err := db.PendingPlaycounts(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0] != 2 || pending[1] != 4 {
+		t.Fatalf("expected players 2 and 4, got %v", pending)
+	}
+
+	if _, err := db.Pool().Exec(ctx, `
+	INSERT INTO user_playcounts (user_id, beatmap_id, playcount, day) VALUES (2, 75, 10, CURRENT_DATE - 1)`,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := []osuapi.BeatmapPlaycount{{BeatmapID: 75, Count: 15}, {BeatmapID: 76, Count: 3}}
+	if err := db.UpdatePlaycounts(ctx, 2, counts); err != nil {
+		t.Fatal(err)
+	}
+
+	var delta int
+	if err := db.Pool().QueryRow(ctx, `
+	SELECT delta FROM user_playcounts WHERE user_id = 2 AND beatmap_id = 75 AND day = CURRENT_DATE`,
+	).Scan(&delta); err != nil {
+		t.Fatal(err)
+	}
+	if delta != 5 {
+		t.Fatalf("expected 5 plays since the last collection, got %d", delta)
+	}
+
+	pending, err = db.PendingPlaycounts(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0] != 4 {
+		t.Fatalf("expected only player 4 to be left, got %v", pending)
+	}
+}
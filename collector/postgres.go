@@ -0,0 +1,1418 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Postgres is the Store backed by the schema in migrations.
+type Postgres struct {
+	pool *pgxpool.Pool
+
+	// QueryTimeout bounds every single call the workers make, so a stalled
+	// database can't block them forever. The bulk loads run at startup and
+	// by the schedulers only end with their context. Zero disables it.
+	QueryTimeout time.Duration
+}
+
+func NewPostgres(ctx context.Context, dsn string) (*Postgres, error) {
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tune for performance:
+	cfg.MaxConns = 20
+	cfg.MinConns = 5
+	cfg.MaxConnIdleTime = time.Minute * 5
+	cfg.HealthCheckPeriod = time.Minute
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Postgres{pool: pool, QueryTimeout: 30 * time.Second}, nil
+}
+
+// timeout derives the context of a single call from ctx.
+func (p *Postgres) timeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.QueryTimeout)
+}
+
+// Pool exposes the underlying connection pool for queries outside of Store.
+func (p *Postgres) Pool() *pgxpool.Pool {
+	return p.pool
+}
+
+func (p *Postgres) Close() {
+	p.pool.Close()
+}
+
+func (p *Postgres) InsertScore(ctx context.Context, s *osuapi.Score, source uint8) error {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO scores (
+			user_id,
+			beatmap,
+			score_id,
+			score,
+			accuracy,
+			max_combo,
+			count_50,
+			count_100,
+			count_300,
+			count_miss,
+			fc,
+			mods,
+			time,
+			rank,
+			passed,
+			pp,
+			mode,
+			added,
+			source
+		) VALUES (
+			$1, $2, $3, $4, $5,
+			$6, $7, $8, $9, $10,
+			$11, $12, $13, $14,
+			$15, $16, $17, $18,
+			$19
+		) ON CONFLICT (score_id, time) DO NOTHING
+		`,
+		s.UserID,
+		s.BeatmapID,
+		s.ID,
+		s.TotalScore,
+		s.Accuracy*100,
+		s.MaxCombo,
+		s.Statistics.Meh,
+		s.Statistics.Ok,
+		s.Statistics.Great,
+		s.Statistics.Miss,
+		s.IsPerfectCombo,
+		convertMods(s.Mods),
+		s.EndedAt,
+		s.Rank,
+		s.Passed,
+		s.PP,
+		s.RulesetID,
+		time.Now(),
+		source,
+	)
+
+	return err
+}
+
+func (p *Postgres) InsertBeatmap(ctx context.Context, b *osuapi.BeatmapExtended, s *osuapi.Beatmapset) error {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO beatmaps (
+			beatmap_id,
+			beatmapset_id,
+			title,
+			artist,
+			creator,
+			creator_id,
+			version,
+			length,
+			ranked,
+			last_update,
+			added
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8,
+			$9,
+			NOW(),
+			NOW()
+		)
+		ON CONFLICT (beatmap_id) DO UPDATE
+		SET
+			beatmapset_id = EXCLUDED.beatmapset_id,
+			title         = EXCLUDED.title,
+			artist        = EXCLUDED.artist,
+			creator       = EXCLUDED.creator,
+			creator_id    = EXCLUDED.creator_id,
+			version       = EXCLUDED.version,
+			length        = EXCLUDED.length,
+			ranked        = EXCLUDED.ranked,
+			last_update = CASE
+				WHEN (
+					beatmaps.beatmapset_id IS DISTINCT FROM EXCLUDED.beatmapset_id OR
+					beatmaps.title         IS DISTINCT FROM EXCLUDED.title OR
+					beatmaps.artist        IS DISTINCT FROM EXCLUDED.artist OR
+					beatmaps.creator       IS DISTINCT FROM EXCLUDED.creator OR
+					beatmaps.creator_id    IS DISTINCT FROM EXCLUDED.creator_id OR
+					beatmaps.version       IS DISTINCT FROM EXCLUDED.version OR
+					beatmaps.length        IS DISTINCT FROM EXCLUDED.length OR
+					beatmaps.ranked        IS DISTINCT FROM EXCLUDED.ranked
+				)
+				THEN NOW()
+				ELSE beatmaps.last_update
+			END;
+	`,
+		b.ID,
+		s.ID,
+		s.Title,
+		s.Artist,
+		s.Creator,
+		b.UserID,
+		b.Version,
+		b.HitLength,
+		b.Ranked,
+	)
+
+	return err
+}
+
+func (p *Postgres) KnownScores(ctx context.Context, ids []int) (map[int]struct{}, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	known := make(map[int]struct{})
+
+	rows, err := p.pool.Query(ctx, `
+	SELECT score_id FROM scores WHERE score_id = ANY($1::bigint[])`,
+		ids,
+	)
+	if err != nil {
+		return known, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return known, err
+		}
+		known[id] = struct{}{}
+	}
+
+	return known, rows.Err()
+}
+
+func (p *Postgres) StoredScores(ctx context.Context, id int, mode int, from time.Time, to time.Time) ([]StoredScore, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	rows, err := p.pool.Query(ctx, `
+	SELECT score_id, time, source
+	FROM scores
+	WHERE user_id = $1
+	AND mode = $2
+	AND time BETWEEN $3 AND $4;
+	`,
+		id,
+		mode,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stored := make([]StoredScore, 0)
+
+	for rows.Next() {
+		var s StoredScore
+		if err := rows.Scan(&s.ID, &s.Time, &s.Source); err != nil {
+			return nil, err
+		}
+		stored = append(stored, s)
+	}
+
+	return stored, rows.Err()
+}
+
+func (p *Postgres) CreateUser(ctx context.Context, u *osuapi.UserExtended) error {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	_, err := p.pool.Exec(ctx, `
+    INSERT INTO users (
+        user_id,
+        username,
+        username_safe,
+        country
+    ) VALUES (
+        $1, $2, $3, $4
+    ) ON CONFLICT (user_id) DO NOTHING
+	`,
+		u.ID,
+		u.Username,
+		u.Safename(),
+		u.CountryCode,
+	)
+
+	return err
+}
+
+func (p *Postgres) UpdateUser(ctx context.Context, u *osuapi.UserExtended) error {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	_, err := p.pool.Exec(ctx, `
+    UPDATE users SET username = $1, username_safe = $2, country = $3, restricted = 0 WHERE user_id = $4`,
+		u.Username,
+		u.Safename(),
+		u.CountryCode,
+		u.ID,
+	)
+
+	return err
+}
+
+func (p *Postgres) RestrictUser(ctx context.Context, id int) (string, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	var username string
+	err := p.pool.QueryRow(ctx, `
+    UPDATE users SET restricted = 1 WHERE user_id = $1 RETURNING username`,
+		id,
+	).Scan(&username)
+
+	return username, err
+}
+
+// PeakStats returns the highest ranks and pp stored for the user. Imported
+// rank history only has the global rank, so it is left out.
+func (p *Postgres) PeakStats(ctx context.Context, id int) (osuapi.UserStatistics, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	stats := osuapi.UserStatistics{}
+
+	err := p.pool.QueryRow(
+		ctx,
+		`
+		SELECT COALESCE(MAX(country), 0), COALESCE(MAX(global), 0), COALESCE(MAX(pp), 0)
+		FROM stats
+		WHERE user_id = $1 AND NOT imported
+		LIMIT 1
+		`, id,
+	).Scan(&stats.CountryRank, &stats.GlobalRank, &stats.PP)
+
+	if err == pgx.ErrNoRows {
+		err = nil
+	}
+
+	return stats, err
+}
+
+// LastUpdate returns when the stats of the user were last written.
+func (p *Postgres) LastUpdate(ctx context.Context, id int) (time.Time, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	var last time.Time
+	err := p.pool.QueryRow(ctx, `
+	SELECT last_update FROM users WHERE user_id = $1`,
+		id,
+	).Scan(&last)
+
+	if err == pgx.ErrNoRows {
+		err = nil
+	}
+
+	return last, err
+}
+
+func (p *Postgres) LoadUsers(ctx context.Context) ([]int, error) {
+	rows, err := p.pool.Query(ctx,
+		"SELECT user_id FROM users WHERE restricted = 0;",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := make([]int, 0)
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (p *Postgres) LoadQueue(ctx context.Context) ([]UserMode, error) {
+	rows, err := p.pool.Query(ctx, `
+	SELECT
+		t.user_id,
+		t.mode
+	FROM (
+		SELECT DISTINCT ON (s.user_id, s.mode)
+			s.user_id,
+			s.mode,
+			s.time
+		FROM scores s
+		JOIN users u ON u.user_id = s.user_id
+		WHERE u.restricted = 0
+		AND s.time > u.last_update
+		ORDER BY
+			s.user_id,
+			s.mode,
+			s.time ASC
+	) t
+	ORDER BY t.time ASC;
+    `)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanUserModes(rows)
+}
+
+func scanUserModes(rows pgx.Rows) ([]UserMode, error) {
+	pending := make([]UserMode, 0)
+
+	for rows.Next() {
+		var p UserMode
+		if err := rows.Scan(&p.UserID, &p.Mode); err != nil {
+			return pending, err
+		}
+		pending = append(pending, p)
+	}
+
+	return pending, rows.Err()
+}
+
+func (p *Postgres) HasHistory(ctx context.Context, id int, mode int) (bool, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := p.pool.QueryRow(ctx, `
+	SELECT EXISTS (SELECT 1 FROM stats WHERE user_id = $1 AND mode = $2)`,
+		id,
+		mode,
+	).Scan(&exists)
+
+	return exists, err
+}
+
+// ImportRankHistory stores imported global ranks. The days are counted back
+// from CURRENT_DATE, the same day UpdateHistory writes to, whatever the
+// timezone of the session is.
+func (p *Postgres) ImportRankHistory(ctx context.Context, id int, mode int, ranks []int, daysAgo []int) error {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	_, err := p.pool.Exec(ctx, `
+	INSERT INTO stats (
+		user_id, mode, global, accuracy,
+		playcount, playtime, score, hits, level,
+		progress, day, imported
+	)
+	SELECT $1, $2, t.global, 0, 0, 0, 0, 0, 0, 0, CURRENT_DATE - t.days_ago, true
+	FROM unnest($3::integer[], $4::integer[]) AS t(global, days_ago)
+	ON CONFLICT (user_id, mode, day) DO NOTHING;
+    `,
+		id,
+		mode,
+		ranks,
+		daysAgo,
+	)
+
+	return err
+}
+
+func (p *Postgres) UpdateHistory(ctx context.Context, id int, mode int, u *osuapi.UserStatistics) error {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	global := 999999999
+	if u.GlobalRank != nil {
+		global = *u.GlobalRank
+	}
+
+	country := 999999999
+	if u.CountryRank != nil {
+		country = *u.CountryRank
+	}
+
+	_, err := p.pool.Exec(ctx, `
+	INSERT INTO stats (
+		user_id, mode, global, country, pp, accuracy,
+		playcount, playtime, score, hits, level,
+		progress, replays_watched, ranked_score
+	)
+	VALUES (
+		$1, $2, $3, $4, $5, $6,
+        $7, $8, $9, $10, $11,
+        $12, $13, $14
+	)
+	ON CONFLICT (user_id, mode, day)
+	DO UPDATE SET
+		global           = EXCLUDED.global,
+		country          = EXCLUDED.country,
+		pp               = EXCLUDED.pp,
+		accuracy         = EXCLUDED.accuracy,
+		playcount        = EXCLUDED.playcount,
+		playtime         = EXCLUDED.playtime,
+		score            = EXCLUDED.score,
+		hits             = EXCLUDED.hits,
+		level            = EXCLUDED.level,
+		progress         = EXCLUDED.progress,
+		replays_watched  = EXCLUDED.replays_watched,
+		ranked_score     = EXCLUDED.ranked_score,
+		imported         = false
+	WHERE
+			stats.imported
+		OR  stats.global          IS DISTINCT FROM EXCLUDED.global
+		OR  stats.country         IS DISTINCT FROM EXCLUDED.country
+		OR  stats.pp              IS DISTINCT FROM EXCLUDED.pp
+		OR  stats.accuracy        IS DISTINCT FROM EXCLUDED.accuracy
+		OR  stats.playcount       IS DISTINCT FROM EXCLUDED.playcount
+		OR  stats.playtime        IS DISTINCT FROM EXCLUDED.playtime
+		OR  stats.score           IS DISTINCT FROM EXCLUDED.score
+		OR  stats.hits            IS DISTINCT FROM EXCLUDED.hits
+		OR  stats.level           IS DISTINCT FROM EXCLUDED.level
+		OR  stats.progress        IS DISTINCT FROM EXCLUDED.progress
+		OR  stats.replays_watched IS DISTINCT FROM EXCLUDED.replays_watched
+		OR  stats.ranked_score    IS DISTINCT FROM EXCLUDED.ranked_score;
+    `,
+		id,
+		mode,
+		global,
+		country,
+		u.PP,
+		u.HitAccuracy,
+		u.PlayCount,
+		u.PlayTime,
+		u.TotalScore,
+		u.TotalHits,
+		u.Level.Current,
+		u.MaximumCombo,
+		u.ReplaysWatchedByOthers,
+		u.RankedScore,
+	)
+
+	return err
+}
+
+func (p *Postgres) UpdateBase(ctx context.Context, u *osuapi.UserExtended) error {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	_, err := p.pool.Exec(ctx, `
+	INSERT INTO stats_base (
+		user_id,
+		badges,
+		followers,
+		achievements
+	)
+	VALUES (
+		$1, $2, $3, $4
+	)
+	ON CONFLICT (user_id, day)
+	DO UPDATE SET
+		badges        = EXCLUDED.badges,
+		banners       = EXCLUDED.banners,
+		followers     = EXCLUDED.followers,
+		achievements  = EXCLUDED.achievements
+	WHERE
+			stats_base.badges       IS DISTINCT FROM EXCLUDED.badges
+		OR  stats_base.banners      IS DISTINCT FROM EXCLUDED.banners
+		OR  stats_base.followers    IS DISTINCT FROM EXCLUDED.followers
+		OR  stats_base.achievements IS DISTINCT FROM EXCLUDED.achievements;
+    `,
+		u.ID,
+		len(u.Badges),
+		u.FollowerCount,
+		len(u.UserAchievements),
+	)
+
+	return err
+}
+
+func (p *Postgres) SnapshotBest(ctx context.Context, id int, mode int, scores []osuapi.Score) error {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	ids := make([]int, len(scores))
+	times := make([]time.Time, len(scores))
+	pps := make([]float64, len(scores))
+
+	for i, score := range scores {
+		ids[i] = score.ID
+		times[i] = score.EndedAt
+		pps[i] = score.PP
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+	DELETE FROM user_best WHERE user_id = $1 AND mode = $2 AND day = CURRENT_DATE`,
+		id,
+		mode,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+	INSERT INTO user_best (user_id, mode, position, score_id, score_time, pp)
+	SELECT $1, $2, t.position, t.score_id, t.score_time, t.pp
+	FROM unnest($3::bigint[], $4::timestamptz[], $5::real[]) WITH ORDINALITY AS t(score_id, score_time, pp, position);
+	`,
+		id,
+		mode,
+		ids,
+		times,
+		pps,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+	INSERT INTO best_snapshots (user_id, mode, updated_at) VALUES ($1, $2, now())
+	ON CONFLICT (user_id, mode) DO UPDATE SET updated_at = EXCLUDED.updated_at`,
+		id,
+		mode,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// PendingBest returns every mode a tracked user has stats in whose best
+// scores weren't snapshotted within the given amount of days.
+func (p *Postgres) PendingBest(ctx context.Context, days int) ([]UserMode, error) {
+	rows, err := p.pool.Query(ctx, `
+	SELECT DISTINCT s.user_id, s.mode
+	FROM stats s
+	JOIN users u ON u.user_id = s.user_id
+	LEFT JOIN best_snapshots b ON b.user_id = s.user_id AND b.mode = s.mode
+	WHERE u.restricted = 0
+	AND (b.updated_at IS NULL OR b.updated_at < now() - make_interval(days => $1::integer));
+	`, days)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanUserModes(rows)
+}
+
+// UpdatePlaycounts stores every playcount that changed since the last run
+// together with the difference to it.
+func (p *Postgres) UpdatePlaycounts(ctx context.Context, id int, counts []osuapi.BeatmapPlaycount) error {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	ids := make([]int, len(counts))
+	plays := make([]int, len(counts))
+
+	for i, c := range counts {
+		ids[i] = c.BeatmapID
+		plays[i] = c.Count
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+	INSERT INTO user_playcounts (user_id, beatmap_id, playcount, delta)
+	SELECT $1, t.beatmap_id, t.playcount, COALESCE(t.playcount - p.playcount, 0)
+	FROM unnest($2::integer[], $3::integer[]) AS t(beatmap_id, playcount)
+	LEFT JOIN LATERAL (
+		SELECT c.playcount
+		FROM user_playcounts c
+		WHERE c.user_id = $1
+		AND c.beatmap_id = t.beatmap_id
+		AND c.day < CURRENT_DATE
+		ORDER BY c.day DESC
+		LIMIT 1
+	) p ON true
+	WHERE p.playcount IS DISTINCT FROM t.playcount
+	ON CONFLICT (user_id, beatmap_id, day)
+	DO UPDATE SET
+		playcount = EXCLUDED.playcount,
+		delta     = EXCLUDED.delta;
+	`,
+		id,
+		ids,
+		plays,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+	UPDATE users SET last_playcounts = NOW() WHERE user_id = $1`,
+		id,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// PendingPlaycounts returns every user that played since their last
+// playcount collection, but at most once every given amount of days.
+func (p *Postgres) PendingPlaycounts(ctx context.Context, days int) ([]int, error) {
+	rows, err := p.pool.Query(ctx, `
+	SELECT user_id
+	FROM users
+	WHERE restricted = 0
+	AND (
+		last_playcounts IS NULL
+		OR (
+			last_update > last_playcounts
+			AND last_playcounts < NOW() - make_interval(days => $1)
+		)
+	)
+	ORDER BY last_playcounts ASC NULLS FIRST;
+	`, days)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := make([]int, 0)
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// CreatePartitions makes sure the monthly scores partitions from the month of
+// from up to months after it exist. It returns how many had to be created.
+func (p *Postgres) CreatePartitions(ctx context.Context, from time.Time, months int) (int, error) {
+	first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	created := 0
+
+	for i := 0; i <= months; i++ {
+		ok, err := p.createPartition(ctx, first.AddDate(0, i, 0))
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+
+	return created, nil
+}
+
+// createPartition creates the scores partition of the month starting at
+// month, reporting whether it didn't exist yet. Moving the scores of that
+// month out of scores_default can take a while, so every partition gets the
+// whole timeout.
+func (p *Postgres) createPartition(ctx context.Context, month time.Time) (bool, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	var ok bool
+	err := p.pool.QueryRow(ctx, `SELECT create_scores_partition($1)`, month).Scan(&ok)
+	return ok, err
+}
+
+// ArchiveScores deletes the scores matching rule that were set before cutoff.
+// The deleted rows of every partition are written to the archive opened for
+// it as CSV, and the deletion is rolled back unless the archive was written
+// completely. It returns how many scores were archived.
+func (p *Postgres) ArchiveScores(ctx context.Context, rule RetentionRule, cutoff time.Time, open func(partition string) (Archive, error)) (int64, error) {
+	partitions, err := p.scorePartitions(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	filter := retentionFilter(rule, cutoff)
+	archived := int64(0)
+
+	for _, partition := range partitions {
+		table := pgx.Identifier{partition}.Sanitize()
+
+		var matches bool
+		if err := p.pool.QueryRow(ctx, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s s WHERE %s)`, table, filter)).Scan(&matches); err != nil {
+			return archived, err
+		}
+		if !matches {
+			continue
+		}
+
+		count, err := p.archivePartition(ctx, table, filter, partition, open)
+		if err != nil {
+			return archived, fmt.Errorf("%s: %w", partition, err)
+		}
+		archived += count
+	}
+
+	return archived, nil
+}
+
+func (p *Postgres) archivePartition(ctx context.Context, table string, filter string, partition string, open func(partition string) (Archive, error)) (int64, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	archive, err := open(partition)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Conn().PgConn().CopyTo(ctx, archive, fmt.Sprintf(
+		`COPY (DELETE FROM %s s WHERE %s RETURNING s.*) TO STDOUT WITH (FORMAT csv, HEADER)`,
+		table, filter,
+	))
+	if err != nil {
+		archive.Abort()
+		return 0, err
+	}
+
+	if err := archive.Close(); err != nil {
+		archive.Abort()
+		return 0, err
+	}
+
+	// The archive only counts once the scores in it are gone, so a failed
+	// commit doesn't leave them archived twice by the next run
+	if err := tx.Commit(ctx); err != nil {
+		archive.Abort()
+		return 0, err
+	}
+
+	return tag.RowsAffected(), archive.Keep()
+}
+
+// scorePartitions lists the partitions of scores that can hold scores set
+// before cutoff.
+func (p *Postgres) scorePartitions(ctx context.Context, cutoff time.Time) ([]string, error) {
+	rows, err := p.pool.Query(ctx, `
+	SELECT c.relname
+	FROM pg_inherits i
+	JOIN pg_class c ON c.oid = i.inhrelid
+	WHERE i.inhparent = 'scores'::regclass
+	ORDER BY c.relname`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]string, 0, len(names))
+	for _, name := range names {
+		month, err := time.Parse("scores_2006_01", name)
+		if err == nil && !month.Before(cutoff) {
+			continue
+		}
+		partitions = append(partitions, name)
+	}
+
+	return partitions, nil
+}
+
+// retentionFilter turns rule into a condition on scores aliased as s. COPY
+// doesn't take parameters, so everything is formatted into the query.
+func retentionFilter(rule RetentionRule, cutoff time.Time) string {
+	conditions := []string{
+		fmt.Sprintf(`s.time < '%s'`, cutoff.UTC().Format(time.RFC3339Nano)),
+		// Best snapshots reference their scores, which keeps them
+		`NOT EXISTS (SELECT 1 FROM user_best ub WHERE ub.score_id = s.score_id AND ub.score_time = s.time)`,
+	}
+
+	if rule.Passed != nil {
+		conditions = append(conditions, fmt.Sprintf(`s.passed = %t`, *rule.Passed))
+	}
+
+	if len(rule.Modes) > 0 {
+		conditions = append(conditions, fmt.Sprintf(`s.mode IN (%s)`, JoinInts(rule.Modes, ", ")))
+	}
+
+	if rule.Ranked != nil {
+		ranked := `EXISTS (SELECT 1 FROM beatmaps b WHERE b.beatmap_id = s.beatmap AND b.ranked IN (1, 2))`
+		if !*rule.Ranked {
+			ranked = "NOT " + ranked
+		}
+		conditions = append(conditions, ranked)
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// SnapshotLeaderboards replaces the leaderboards of day in mode with the top
+// size players of every kind, globally and per country. Every player is
+// ranked by their latest stats up to that day.
+func (p *Postgres) SnapshotLeaderboards(ctx context.Context, day time.Time, mode int, size int) (int64, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM leaderboards WHERE day = $1 AND mode = $2`, day, mode); err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, `
+	WITH latest AS (
+		SELECT DISTINCT ON (s.user_id)
+			s.user_id,
+			u.country,
+			s.pp,
+			s.ranked_score,
+			s.playcount,
+			s.accuracy
+		FROM stats s
+		JOIN users u ON u.user_id = s.user_id
+		WHERE s.mode = $2
+		AND s.day <= $1
+		AND NOT s.imported
+		AND u.restricted = 0
+		ORDER BY s.user_id, s.day DESC
+	),
+	ranked AS (
+		SELECT
+			l.user_id,
+			l.country,
+			v.kind,
+			v.value,
+			row_number() OVER (PARTITION BY v.kind ORDER BY v.value DESC, l.user_id) AS global_position,
+			row_number() OVER (PARTITION BY v.kind, l.country ORDER BY v.value DESC, l.user_id) AS country_position
+		FROM latest l
+		CROSS JOIN LATERAL (VALUES
+			('pp', l.pp::double precision),
+			('ranked_score', l.ranked_score::double precision),
+			('playcount', l.playcount::double precision),
+			-- Only ranked players, or a single play could top it
+			('accuracy', CASE WHEN l.pp > 0 THEN l.accuracy::double precision END)
+		) AS v(kind, value)
+		WHERE v.value > 0
+	),
+	entries AS (
+		SELECT '' AS country, kind, global_position AS position, user_id, value
+		FROM ranked
+		WHERE global_position <= $3
+		UNION ALL
+		SELECT country, kind, country_position AS position, user_id, value
+		FROM ranked
+		WHERE country_position <= $3
+		AND trim(country) <> ''
+	)
+	INSERT INTO leaderboards (day, mode, country, kind, position, user_id, value, change)
+	SELECT $1, $2, e.country, e.kind, e.position, e.user_id, e.value, prev.position - e.position
+	FROM entries e
+	LEFT JOIN leaderboards prev
+		ON prev.day = $1::date - 1
+		AND prev.mode = $2
+		AND prev.country = e.country
+		AND prev.kind = e.kind
+		AND prev.user_id = e.user_id;
+	`,
+		day,
+		mode,
+		size,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
+
+// Leaderboard reads a snapshot ordered by position. An empty country is the
+// global leaderboard.
+func (p *Postgres) Leaderboard(ctx context.Context, day time.Time, mode int, country string, kind string) ([]LeaderboardEntry, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	rows, err := p.pool.Query(ctx, `
+	SELECT position, user_id, value, change
+	FROM leaderboards
+	WHERE day = $1
+	AND mode = $2
+	AND country = $3
+	AND kind = $4
+	ORDER BY position;
+	`,
+		day,
+		mode,
+		country,
+		kind,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[LeaderboardEntry])
+}
+
+// BeatmapLeaderboard ranks the best score of every player on a beatmap the
+// way osu! does: by score, and the earlier score on ties. Restricted players
+// are left out.
+func (p *Postgres) BeatmapLeaderboard(ctx context.Context, opts BeatmapLeaderboardOptions) ([]BeatmapScore, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := p.pool.Query(ctx, `
+	WITH best AS (
+		SELECT DISTINCT ON (s.user_id)
+			s.score_id,
+			s.user_id,
+			s.score,
+			s.accuracy,
+			s.max_combo,
+			s.pp,
+			s.mods,
+			s.rank,
+			s.passed,
+			s.time
+		FROM scores s
+		WHERE s.beatmap = $1
+		AND s.mode = $2
+		AND ($4 OR s.passed)
+		AND (
+			$3::text[] IS NULL
+			OR (
+				array_remove(COALESCE(s.mods, '{}'), 'CL') @> $3::text[]
+				AND array_remove(COALESCE(s.mods, '{}'), 'CL') <@ $3::text[]
+			)
+		)
+		ORDER BY s.user_id, s.score DESC, s.time ASC, s.score_id ASC
+	)
+	SELECT
+		row_number() OVER (ORDER BY b.score DESC, b.time ASC, b.score_id ASC) AS position,
+		b.score_id,
+		b.user_id,
+		COALESCE(u.username, ''),
+		COALESCE(b.score, 0),
+		b.accuracy,
+		COALESCE(b.max_combo, 0),
+		COALESCE(b.pp, 0),
+		COALESCE(b.mods, '{}'),
+		COALESCE(b.rank, ''),
+		b.passed,
+		b.time
+	FROM best b
+	LEFT JOIN users u ON u.user_id = b.user_id
+	WHERE COALESCE(u.restricted, 0) = 0
+	ORDER BY position
+	LIMIT $5 OFFSET $6;
+	`,
+		opts.Beatmap,
+		opts.Mode,
+		normalizeMods(opts.Mods),
+		opts.IncludeFailed,
+		limit,
+		opts.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[BeatmapScore])
+}
+
+// activityOverlap is how many score ids before the last aggregated one are
+// aggregated again, so scores committed out of order aren't missed.
+const activityOverlap = 1000
+
+// AggregateActivity brings user_daily_activity up to date. The days of scores
+// inserted since the last run are recounted, batch ids at a time, and the pp
+// and playtime gains of stats since the last run's day are recalculated. It
+// returns how many days of players were updated.
+func (p *Postgres) AggregateActivity(ctx context.Context, batch int) (int64, error) {
+	var lastID int64
+	var lastDay *time.Time
+	err := p.pool.QueryRow(ctx, `
+	SELECT last_id, last_day FROM aggregation_state WHERE name = 'user_daily_activity'`,
+	).Scan(&lastID, &lastDay)
+	if err != nil && err != pgx.ErrNoRows {
+		return 0, err
+	}
+
+	var maxID int64
+	if err := p.pool.QueryRow(ctx, `SELECT COALESCE(max(id), 0) FROM scores`).Scan(&maxID); err != nil {
+		return 0, err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	updated := int64(0)
+
+	for from := max(lastID-activityOverlap, 0); from < maxID; from += int64(batch) {
+		to := min(from+int64(batch), maxID)
+
+		tag, err := p.pool.Exec(ctx, `
+		WITH days AS (
+			SELECT DISTINCT user_id, mode, (time AT TIME ZONE 'UTC')::date AS day
+			FROM scores
+			WHERE id > $1 AND id <= $2
+		)
+		INSERT INTO user_daily_activity (user_id, mode, day, plays, passes, total_score, distinct_beatmaps)
+		SELECT
+			d.user_id,
+			d.mode,
+			d.day,
+			count(*),
+			count(*) FILTER (WHERE s.passed),
+			COALESCE(sum(s.score), 0),
+			count(DISTINCT s.beatmap)
+		FROM days d
+		JOIN scores s
+			ON s.user_id = d.user_id
+			AND s.mode = d.mode
+			AND s.time >= d.day::timestamp AT TIME ZONE 'UTC'
+			AND s.time < (d.day + 1)::timestamp AT TIME ZONE 'UTC'
+		GROUP BY d.user_id, d.mode, d.day
+		ON CONFLICT (user_id, mode, day) DO UPDATE SET
+			plays             = EXCLUDED.plays,
+			passes            = EXCLUDED.passes,
+			total_score       = EXCLUDED.total_score,
+			distinct_beatmaps = EXCLUDED.distinct_beatmaps;
+		`,
+			from,
+			to,
+		)
+		if err != nil {
+			return updated, err
+		}
+		updated += tag.RowsAffected()
+
+		if err := p.saveAggregation(ctx, to, lastDay); err != nil {
+			return updated, err
+		}
+	}
+
+	since := time.Time{}
+	if lastDay != nil {
+		since = *lastDay
+	}
+
+	tag, err := p.pool.Exec(ctx, `
+	INSERT INTO user_daily_activity (user_id, mode, day, pp_gained, playtime)
+	SELECT
+		s.user_id,
+		s.mode,
+		s.day,
+		s.pp - prev.pp,
+		GREATEST(s.playtime - prev.playtime, 0)
+	FROM stats s
+	CROSS JOIN LATERAL (
+		SELECT p.pp, p.playtime
+		FROM stats p
+		WHERE p.user_id = s.user_id
+		AND p.mode = s.mode
+		AND p.day < s.day
+		AND NOT p.imported
+		ORDER BY p.day DESC
+		LIMIT 1
+	) prev
+	WHERE s.day >= $1
+	AND NOT s.imported
+	ON CONFLICT (user_id, mode, day) DO UPDATE SET
+		pp_gained = EXCLUDED.pp_gained,
+		playtime  = EXCLUDED.playtime;
+	`,
+		since,
+	)
+	if err != nil {
+		return updated, err
+	}
+	updated += tag.RowsAffected()
+
+	return updated, p.saveAggregation(ctx, max(lastID, maxID), &today)
+}
+
+func (p *Postgres) saveAggregation(ctx context.Context, lastID int64, lastDay *time.Time) error {
+	_, err := p.pool.Exec(ctx, `
+	INSERT INTO aggregation_state (name, last_id, last_day)
+	VALUES ('user_daily_activity', $1, $2)
+	ON CONFLICT (name) DO UPDATE SET
+		last_id  = EXCLUDED.last_id,
+		last_day = EXCLUDED.last_day;
+	`,
+		lastID,
+		lastDay,
+	)
+	return err
+}
+
+// Activity reads the daily activity of a player between from and to,
+// inclusive.
+func (p *Postgres) Activity(ctx context.Context, id int, mode int, from time.Time, to time.Time) ([]DailyActivity, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	rows, err := p.pool.Query(ctx, `
+	SELECT day, plays, passes, total_score, pp_gained, playtime, distinct_beatmaps
+	FROM user_daily_activity
+	WHERE user_id = $1
+	AND mode = $2
+	AND day BETWEEN $3 AND $4
+	ORDER BY day;
+	`,
+		id,
+		mode,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[DailyActivity])
+}
+
+// ComputeGains replaces the gains of the period from start to end in mode.
+// Players without stats before or within the period are left out.
+func (p *Postgres) ComputeGains(ctx context.Context, period string, start time.Time, end time.Time, mode int) (int64, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+	DELETE FROM user_gains WHERE period = $1 AND start = $2 AND mode = $3`,
+		period, start, mode,
+	); err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, `
+	WITH latest AS (
+		SELECT DISTINCT ON (s.user_id)
+			s.user_id,
+			s.pp,
+			s.global,
+			s.playcount
+		FROM stats s
+		WHERE s.mode = $3
+		AND s.day >= $2
+		AND s.day < $4
+		AND NOT s.imported
+		ORDER BY s.user_id, s.day DESC
+	)
+	INSERT INTO user_gains (period, start, mode, user_id, country, pp, rank, playcount)
+	SELECT
+		$1,
+		$2,
+		$3,
+		l.user_id,
+		trim(u.country),
+		l.pp - b.pp,
+		CASE
+			WHEN l.global < 999999999 AND b.global < 999999999 THEN b.global - l.global
+		END,
+		l.playcount - b.playcount
+	FROM latest l
+	JOIN users u ON u.user_id = l.user_id
+	CROSS JOIN LATERAL (
+		SELECT pp, global, playcount
+		FROM stats b
+		WHERE b.user_id = l.user_id
+		AND b.mode = $3
+		AND b.day < $2
+		AND NOT b.imported
+		ORDER BY b.day DESC
+		LIMIT 1
+	) b
+	WHERE u.restricted = 0;
+	`,
+		period,
+		start,
+		mode,
+		end,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
+
+var gainColumns = map[string]string{
+	"pp":        "g.pp",
+	"rank":      "g.rank",
+	"playcount": "g.playcount",
+}
+
+// TopGainers lists the players that gained the most over a period.
+func (p *Postgres) TopGainers(ctx context.Context, opts TopGainersOptions) ([]Gain, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	start, err := PeriodStart(opts.Period, opts.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	by, ok := gainColumns[opts.By]
+	if !ok {
+		return nil, fmt.Errorf("unknown gain %q", opts.By)
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := p.pool.Query(ctx, fmt.Sprintf(`
+	SELECT g.user_id, COALESCE(u.username, ''), g.country, g.pp, g.rank, g.playcount
+	FROM user_gains g
+	LEFT JOIN users u ON u.user_id = g.user_id
+	WHERE g.period = $1
+	AND g.start = $2
+	AND g.mode = $3
+	AND ($4 = '' OR g.country = $4)
+	AND %[1]s > 0
+	ORDER BY %[1]s DESC, g.user_id
+	LIMIT $5;
+	`, by),
+		opts.Period,
+		start,
+		opts.Mode,
+		opts.Country,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Gain])
+}
+
+// LatestStats returns the newest stats of the user that came from an update,
+// nil if there are none yet. Ranks of unranked players are nil.
+func (p *Postgres) LatestStats(ctx context.Context, id int, mode int) (*osuapi.UserStatistics, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	var global, country int
+	stats := &osuapi.UserStatistics{}
+
+	err := p.pool.QueryRow(ctx, `
+	SELECT COALESCE(global, 0), COALESCE(country, 0), COALESCE(pp, 0)
+	FROM stats
+	WHERE user_id = $1 AND mode = $2 AND NOT imported
+	ORDER BY day DESC
+	LIMIT 1;
+	`,
+		id,
+		mode,
+	).Scan(&global, &country, &stats.PP)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if global > 0 && global < 999999999 {
+		stats.GlobalRank = &global
+	}
+	if country > 0 && country < 999999999 {
+		stats.CountryRank = &country
+	}
+
+	return stats, nil
+}
+
+// TopPlay returns the most pp any passed score of the user other than
+// exclude is worth, out of the stored scores and the newest best snapshot of
+// the mode. Older snapshots can hold scores that lost pp since.
+func (p *Postgres) TopPlay(ctx context.Context, id int, mode int, exclude int) (float64, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	var top float64
+	err := p.pool.QueryRow(ctx, `
+	SELECT GREATEST(
+		(SELECT COALESCE(MAX(pp), 0) FROM scores WHERE user_id = $1 AND mode = $2 AND passed AND score_id <> $3),
+		(
+			SELECT COALESCE(MAX(pp), 0) FROM user_best
+			WHERE user_id = $1 AND mode = $2 AND score_id <> $3
+			AND day = (SELECT MAX(day) FROM user_best WHERE user_id = $1 AND mode = $2)
+		)
+	);
+	`,
+		id,
+		mode,
+		exclude,
+	).Scan(&top)
+
+	return top, err
+}
+
+// MarkMilestone records that a rule notified about key and reports whether
+// it wasn't recorded before.
+func (p *Postgres) MarkMilestone(ctx context.Context, rule string, id int, mode int, key string) (bool, error) {
+	ctx, cancel := p.timeout(ctx)
+	defer cancel()
+
+	tag, err := p.pool.Exec(ctx, `
+	INSERT INTO milestones_sent (rule, user_id, mode, key)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT DO NOTHING;
+	`,
+		rule,
+		id,
+		mode,
+		key,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
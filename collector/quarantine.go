@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quarantine keeps payloads that couldn't be parsed, one timestamped file
+// each, so failures can be inspected later. Only the newest MaxFiles are kept.
+type Quarantine struct {
+	Dir      string
+	MaxFiles int
+
+	mu sync.Mutex
+}
+
+type quarantined struct {
+	Time     time.Time `json:"time"`
+	Endpoint string    `json:"endpoint"`
+	Error    string    `json:"error"`
+	Payload  string    `json:"payload"`
+}
+
+// Save writes the payload and returns the path of the file.
+func (q *Quarantine) Save(endpoint string, payload []byte, cause error) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := os.MkdirAll(q.Dir, 0755); err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	entry := quarantined{
+		Time:     now,
+		Endpoint: endpoint,
+		Error:    cause.Error(),
+		Payload:  string(payload),
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s_%s.json", now.Format("20060102T150405.000000000"), sanitize(endpoint))
+	path := filepath.Join(q.Dir, name)
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+
+	return path, q.rotate()
+}
+
+// rotate removes the oldest files beyond MaxFiles. The timestamp prefix makes
+// the names sort by age.
+func (q *Quarantine) rotate() error {
+	if q.MaxFiles <= 0 {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(q.Dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	if len(files) <= q.MaxFiles {
+		return nil
+	}
+
+	slices.Sort(files)
+
+	for _, file := range files[:len(files)-q.MaxFiles] {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sanitize turns /api/v2/users/2?mode=0 into users_2.
+func sanitize(endpoint string) string {
+	endpoint, _, _ = strings.Cut(endpoint, "?")
+	endpoint = strings.TrimPrefix(endpoint, "/api/v2")
+	endpoint = strings.Trim(endpoint, "/")
+	if endpoint == "" {
+		return "root"
+	}
+	return strings.ReplaceAll(endpoint, "/", "_")
+}
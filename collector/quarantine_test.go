@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuarantineRotates(t *testing.T) {
+	q := &Quarantine{Dir: t.TempDir(), MaxFiles: 3}
+
+	var paths []string
+	for i := 0; i < 5; i++ {
+		path, err := q.Save("/scores?cursor_string=abc", []byte("{broken"), errors.New("unexpected end of JSON input"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(q.Dir, "*.json"))
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files after rotation, got %d", len(files))
+	}
+
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Fatal("the oldest file wasn't removed")
+	}
+
+	if !strings.HasSuffix(paths[4], "_scores.json") {
+		t.Fatalf("unexpected name %s", paths[4])
+	}
+
+	data, err := os.ReadFile(paths[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entry quarantined
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Payload != "{broken" || entry.Error != "unexpected end of JSON input" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+}
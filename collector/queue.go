@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Queue struct {
+	in       chan int
+	priority chan int
+	out      chan int
+
+	flush func(ctx context.Context, id int, modes uint8) error
+
+	// Timeout bounds a single flush. Zero disables it.
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	cache map[int]uint8
+}
+
+// Start dispatches queued ids to the workers until ctx is done.
+func (q *Queue) Start(ctx context.Context) {
+	go func() {
+		for {
+			var id int
+			select {
+			case <-ctx.Done():
+				return
+			case id = <-q.priority:
+			case id = <-q.in:
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case q.out <- id:
+			}
+		}
+	}()
+}
+
+func (q *Queue) Queue(id int, mode uint8, priority bool) {
+	bit := uint8(1 << mode)
+
+	q.mu.Lock()
+	prev := q.cache[id]
+	q.cache[id] = prev | bit
+	q.mu.Unlock()
+
+	if prev == 0 {
+		if priority {
+			q.priority <- id
+		} else {
+			q.in <- id
+		}
+	}
+}
+
+// Len returns how many users are queued or being updated.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.cache)
+}
+
+func (q *Queue) Remove(id int) {
+	q.mu.Lock()
+	delete(q.cache, id)
+	q.mu.Unlock()
+}
+
+// Workers starts n workers flushing ids until ctx is done.
+func (q *Queue) Workers(ctx context.Context, n int) {
+	for i := 0; i < n; i++ {
+		go q.worker(ctx)
+	}
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	for {
+		var id int
+		select {
+		case <-ctx.Done():
+			return
+		case id = <-q.out:
+		}
+
+		q.mu.Lock()
+		modes := q.cache[id]
+		delete(q.cache, id)
+		q.mu.Unlock()
+
+		if modes == 0 {
+			continue
+		}
+
+		if err := q.run(ctx, id, modes); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			q.mu.Lock()
+			q.cache[id] |= modes
+			q.mu.Unlock()
+
+			go func() {
+				q.priority <- id
+			}()
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, id int, modes uint8) error {
+	if q.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.Timeout)
+		defer cancel()
+	}
+	return q.flush(ctx, id, modes)
+}
+
+func createQueue(flush func(ctx context.Context, id int, modes uint8) error, slots, pSlots int) *Queue {
+	q := &Queue{
+		in:       make(chan int, slots),
+		out:      make(chan int, 20),
+		priority: make(chan int, pSlots),
+		flush:    flush,
+		cache:    make(map[int]uint8),
+	}
+
+	return q
+}
@@ -0,0 +1,154 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+type flushRecorder struct {
+	mu      sync.Mutex
+	flushes map[int][]uint8
+	fail    map[int]int
+	done    chan int
+}
+
+func newFlushRecorder() *flushRecorder {
+	return &flushRecorder{
+		flushes: make(map[int][]uint8),
+		fail:    make(map[int]int),
+		done:    make(chan int, 16),
+	}
+}
+
+func (r *flushRecorder) flush(ctx context.Context, id int, modes uint8) error {
+	r.mu.Lock()
+	r.flushes[id] = append(r.flushes[id], modes)
+	failing := r.fail[id] > 0
+	if failing {
+		r.fail[id]--
+	}
+	r.mu.Unlock()
+
+	if failing {
+		return errors.New("flush failed")
+	}
+
+	r.done <- id
+	return nil
+}
+
+func (r *flushRecorder) wait(t *testing.T, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.done:
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of %d flushes happened", i, n)
+		}
+	}
+}
+
+func TestQueueCoalescesModes(t *testing.T) {
+	r := newFlushRecorder()
+	q := createQueue(r.flush, 8, 8)
+
+	q.Queue(1, osuapi.ModeStd, false)
+	q.Queue(1, osuapi.ModeMania, true)
+	q.Queue(2, osuapi.ModeTaiko, false)
+
+	q.Workers(t.Context(), 2)
+	q.Start(t.Context())
+
+	r.wait(t, 2)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if got := r.flushes[1]; len(got) != 1 || got[0] != 1<<osuapi.ModeStd|1<<osuapi.ModeMania {
+		t.Fatalf("expected a single flush of std and mania, got %v", got)
+	}
+
+	if got := r.flushes[2]; len(got) != 1 || got[0] != 1<<osuapi.ModeTaiko {
+		t.Fatalf("expected a single flush of taiko, got %v", got)
+	}
+}
+
+func TestQueueRetriesFailedFlush(t *testing.T) {
+	r := newFlushRecorder()
+	r.fail[1] = 2
+	q := createQueue(r.flush, 8, 8)
+
+	q.Workers(t.Context(), 1)
+	q.Start(t.Context())
+
+	q.Queue(1, osuapi.ModeCatch, false)
+
+	r.wait(t, 1)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if got := r.flushes[1]; len(got) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(got))
+	}
+
+	for _, modes := range r.flushes[1] {
+		if modes != 1<<osuapi.ModeCatch {
+			t.Fatalf("retry lost modes: %v", r.flushes[1])
+		}
+	}
+}
+
+func TestQueueRemove(t *testing.T) {
+	r := newFlushRecorder()
+	q := createQueue(r.flush, 8, 8)
+
+	q.Queue(1, osuapi.ModeStd, false)
+	q.Remove(1)
+	q.Queue(2, osuapi.ModeStd, false)
+
+	q.Workers(t.Context(), 1)
+	q.Start(t.Context())
+
+	r.wait(t, 1)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, flushed := r.flushes[1]; flushed {
+		t.Fatal("removed user was flushed")
+	}
+}
+
+func TestQueueFlushTimeout(t *testing.T) {
+	errs := make(chan error, 1)
+
+	q := createQueue(func(ctx context.Context, id int, modes uint8) error {
+		<-ctx.Done()
+		select {
+		case errs <- ctx.Err():
+		default:
+		}
+		return nil
+	}, 8, 8)
+	q.Timeout = 20 * time.Millisecond
+
+	q.Workers(t.Context(), 1)
+	q.Start(t.Context())
+	q.Queue(1, osuapi.ModeStd, false)
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the flush to time out, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the flush wasn't cancelled")
+	}
+}
@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+type Reconciliation struct {
+	UserID int
+	Mode   int
+	From   time.Time
+	To     time.Time
+
+	// The recent endpoint only covers the last 24 hours, extra scores can
+	// only be told apart from scores the API doesn't list after this.
+	RecentSince time.Time
+
+	Remote  map[int]osuapi.Score
+	Stored  map[int]*int16
+	Missing []int
+	Extra   []int
+}
+
+func (r *Reconciliation) inRange(t time.Time) bool {
+	return !t.Before(r.From) && !t.After(r.To)
+}
+
+// Reconcile compares the scores the API returns for a user within the given
+// time range with the ones stored in the database.
+func (c *Collector) Reconcile(ctx context.Context, id int, mode int, from time.Time, to time.Time) (*Reconciliation, error) {
+	modeStr := osuapi.ModeStr(mode)
+
+	r := &Reconciliation{
+		UserID:      id,
+		Mode:        mode,
+		From:        from,
+		To:          to,
+		RecentSince: time.Now().Add(-24 * time.Hour),
+		Remote:      make(map[int]osuapi.Score),
+		Stored:      make(map[int]*int16),
+	}
+
+	recent, err := c.AllScores(ctx, id, "recent", modeStr, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	best, err := c.GetBest(ctx, id, modeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	firsts, err := c.GetFirsts(ctx, id, modeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, err := c.GetPinned(ctx, id, modeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range [][]osuapi.Score{recent, best, firsts, pinned} {
+		for _, score := range list {
+			if r.inRange(score.EndedAt) {
+				r.Remote[score.ID] = score
+			}
+		}
+	}
+
+	stored, err := c.Store.StoredScores(ctx, id, mode, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range stored {
+		r.Stored[s.ID] = s.Source
+
+		if _, exists := r.Remote[s.ID]; !exists && s.Time.After(r.RecentSince) {
+			r.Extra = append(r.Extra, s.ID)
+		}
+	}
+
+	for scoreID := range r.Remote {
+		if _, exists := r.Stored[scoreID]; !exists {
+			r.Missing = append(r.Missing, scoreID)
+		}
+	}
+
+	return r, nil
+}
+
+// Backfill inserts every missing score.
+func (c *Collector) Backfill(ctx context.Context, r *Reconciliation) (int, error) {
+	inserted := 0
+
+	for _, scoreID := range r.Missing {
+		score := r.Remote[scoreID]
+		if err := c.storeScores(ctx, []osuapi.Score{score}, SourceBackfill); err != nil {
+			return inserted, err
+		}
+		inserted++
+	}
+
+	return inserted, nil
+}
+
+func (r *Reconciliation) Print(w io.Writer) {
+	sources := make(map[string]int)
+	for _, source := range r.Stored {
+		if source == nil {
+			sources["unknown"]++
+			continue
+		}
+		sources[SourceStr(uint8(*source))]++
+	}
+
+	fmt.Fprintf(w, "Reconciled %d on Mode %d from %s to %s\n", r.UserID, r.Mode, r.From.Format(time.RFC3339), r.To.Format(time.RFC3339))
+	fmt.Fprintf(w, "API scores: %d | Stored scores: %d\n", len(r.Remote), len(r.Stored))
+	for source, count := range sources {
+		fmt.Fprintf(w, "  %s: %d\n", source, count)
+	}
+	fmt.Fprintf(w, "Missing (%d): %s\n", len(r.Missing), JoinInts(r.Missing, ", "))
+	fmt.Fprintf(w, "Extra since %s (%d): %s\n", r.RecentSince.Format(time.RFC3339), len(r.Extra), JoinInts(r.Extra, ", "))
+}
+
+func SourceStr(source uint8) string {
+	switch source {
+	case SourceFeed:
+		return "feed"
+	case SourceRecent:
+		return "recent"
+	case SourceBest:
+		return "best"
+	case SourceFirsts:
+		return "firsts"
+	case SourceBackfill:
+		return "backfill"
+	default:
+		return strconv.Itoa(int(source))
+	}
+}
@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+func TestReconcile(t *testing.T) {
+	f := newFakeOsu(t)
+	store := newMemStore()
+	c, _ := testCollector(t, f, store)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	score := func(id int, at time.Time) osuapi.Score {
+		return osuapi.Score{ID: id, UserID: 2, RulesetID: 0, EndedAt: at, Passed: true}
+	}
+
+	// 1 is stored, 2 only known to the api and 3 outside of the range
+	f.Set(t, "/users/2/scores/recent", []osuapi.Score{score(1, now.Add(-time.Hour)), score(2, now.Add(-2*time.Hour))})
+	f.Set(t, "/users/2/scores/best", []osuapi.Score{score(1, now.Add(-time.Hour)), score(3, now.Add(-72*time.Hour))})
+
+	stored := score(1, now.Add(-time.Hour))
+	store.InsertScore(t.Context(), &stored, SourceFeed)
+
+	// Stored recently but not listed by the api anymore
+	extra := score(4, now.Add(-3*time.Hour))
+	store.InsertScore(t.Context(), &extra, SourceRecent)
+
+	r, err := c.Reconcile(t.Context(), 2, 0, now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(r.Missing, []int{2}) {
+		t.Fatalf("expected score 2 to be missing, got %v", r.Missing)
+	}
+	if !slices.Equal(r.Extra, []int{4}) {
+		t.Fatalf("expected score 4 to be extra, got %v", r.Extra)
+	}
+
+	var out bytes.Buffer
+	r.Print(&out)
+	if !strings.Contains(out.String(), "Missing (1): 2\n") {
+		t.Fatalf("expected the missing score to be reported, got:\n%s", out.String())
+	}
+
+	inserted, err := c.Backfill(t.Context(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inserted != 1 {
+		t.Fatalf("expected 1 backfilled score, got %d", inserted)
+	}
+	if source, exists := store.scores[2]; !exists || source != SourceBackfill {
+		t.Fatalf("expected score 2 to be backfilled, got source %d", source)
+	}
+	if store.scores[1] != SourceFeed {
+		t.Fatal("expected the stored score to keep its source")
+	}
+}
@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// RetentionRule selects scores that get archived and deleted. A score has to
+// be older than OlderThanDays and match every other field that is set.
+type RetentionRule struct {
+	Name          string `json:"name"`
+	OlderThanDays int    `json:"older_than_days"`
+	Passed        *bool  `json:"passed,omitempty"` // only passed or only failed scores
+	Ranked        *bool  `json:"ranked,omitempty"` // whether the beatmap is ranked or approved
+	Modes         []int  `json:"modes,omitempty"`  // any mode if empty
+}
+
+var ruleName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+func (r RetentionRule) Validate() error {
+	if !ruleName.MatchString(r.Name) {
+		return fmt.Errorf("retention rule %q: the name may only contain a-z, 0-9, _ and -", r.Name)
+	}
+	if r.OlderThanDays <= 0 {
+		return fmt.Errorf("retention rule %q: older_than_days has to be positive", r.Name)
+	}
+	for _, mode := range r.Modes {
+		if mode < 0 || mode > 3 {
+			return fmt.Errorf("retention rule %q: invalid mode %d", r.Name, mode)
+		}
+	}
+	return nil
+}
+
+// Cutoff is the time scores have to be set before to match.
+func (r RetentionRule) Cutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -r.OlderThanDays)
+}
+
+// LoadRetentionRules reads a JSON list of rules. A missing file means no
+// rules.
+func LoadRetentionRules(path string) ([]RetentionRule, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []RetentionRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	names := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		if _, exists := names[rule.Name]; exists {
+			return nil, fmt.Errorf("retention rule %q is defined twice", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+	}
+
+	return rules, nil
+}
+
+// StartRetention applies the retention rules every RetentionInterval.
+func (c *Collector) StartRetention(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.Config.RetentionInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := c.ApplyRetention(ctx); err != nil {
+				c.Logger.Error("Couldn't apply retention rules", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ApplyRetention archives and deletes the scores matching each rule. The
+// scores of every partition go to their own gzipped CSV file in
+// ArchiveDir/<rule>, and are only deleted once it was written.
+func (c *Collector) ApplyRetention(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	for _, rule := range c.Config.RetentionRules {
+		archived, err := c.Store.ArchiveScores(ctx, rule, rule.Cutoff(now), func(partition string) (Archive, error) {
+			return c.archiveFile(rule.Name, partition, now)
+		})
+		if err != nil {
+			return fmt.Errorf("retention rule %q: %w", rule.Name, err)
+		}
+
+		if archived > 0 {
+			c.Logger.Info("Archived scores", "rule", rule.Name, "count", archived)
+		}
+	}
+
+	return nil
+}
+
+// archiveFile creates the gzipped file the scores of partition are written
+// to. It only gets its final name once the scores in it were deleted.
+func (c *Collector) archiveFile(rule string, partition string, now time.Time) (Archive, error) {
+	dir := filepath.Join(c.Config.ArchiveDir, rule)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s.csv.gz", partition, now.Format("20060102T150405")))
+
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &archiveWriter{Writer: gzip.NewWriter(f), f: f, path: path}, nil
+}
+
+type archiveWriter struct {
+	*gzip.Writer
+	f    *os.File
+	path string
+}
+
+func (w *archiveWriter) Close() error {
+	if err := w.Writer.Close(); err != nil {
+		w.f.Close()
+		return err
+	}
+	if err := w.f.Sync(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// Keep renames the closed file to its final name.
+func (w *archiveWriter) Keep() error {
+	return os.Rename(w.f.Name(), w.path)
+}
+
+// Abort removes the unfinished file.
+func (w *archiveWriter) Abort() {
+	w.f.Close()
+	os.Remove(w.f.Name())
+}
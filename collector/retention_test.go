@@ -0,0 +1,166 @@
+package collector
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+func TestLoadRetentionRules(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(content string) string {
+		path := filepath.Join(dir, "retention.json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rules, err := LoadRetentionRules(filepath.Join(dir, "missing.json"))
+	if err != nil || rules != nil {
+		t.Fatalf("expected no rules without a file, got %v: %v", rules, err)
+	}
+
+	rules, err = LoadRetentionRules(write(`[{"name": "failed", "older_than_days": 30, "passed": false, "modes": [1, 3]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].Passed == nil || *rules[0].Passed || rules[0].Ranked != nil || len(rules[0].Modes) != 2 {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+
+	invalid := []string{
+		`[{"name": "../failed", "older_than_days": 30}]`,
+		`[{"name": "failed", "older_than_days": 0}]`,
+		`[{"name": "failed", "older_than_days": 30, "modes": [4]}]`,
+		`[{"name": "failed", "older_than_days": 30}, {"name": "failed", "older_than_days": 60}]`,
+	}
+	for _, content := range invalid {
+		if _, err := LoadRetentionRules(write(content)); err == nil {
+			t.Errorf("expected %s to be rejected", content)
+		}
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	db := testDB(t)
+	ctx := t.Context()
+
+	old := time.Now().AddDate(0, 0, -60).Truncate(time.Second)
+	for i, score := range []struct {
+		time   time.Time
+		passed bool
+	}{
+		{old, false},
+		{old, true},
+		{time.Now(), false},
+		{old, false}, // still in a best snapshot
+	} {
+		if _, err := db.Pool().Exec(ctx, `
+		INSERT INTO scores (user_id, score_id, accuracy, fc, time, passed, mode)
+		VALUES (2, $1, 100, false, $2, $3, 0)`,
+			i+1, score.time, score.passed,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.SnapshotBest(ctx, 2, 0, []osuapi.Score{{ID: 4, EndedAt: old}}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := testCollector(t, newFakeOsu(t), db)
+	failed := false
+	c.Config.ArchiveDir = t.TempDir()
+	c.Config.RetentionRules = []RetentionRule{{Name: "failed", OlderThanDays: 30, Passed: &failed}}
+
+	if err := c.ApplyRetention(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var left []int
+	rows, err := db.Pool().Query(ctx, `SELECT score_id FROM scores ORDER BY score_id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		left = append(left, id)
+	}
+	if len(left) != 3 || left[0] != 2 || left[1] != 3 || left[2] != 4 {
+		t.Fatalf("expected only the old failed score without a snapshot to be deleted, left %v", left)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(c.Config.ArchiveDir, "failed", "*.csv.gz"))
+	if len(files) != 1 {
+		t.Fatalf("expected one archive, got %v", files)
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(gz).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0][0] != "id" {
+		t.Fatalf("expected a header and one score, got %v", records)
+	}
+}
+
+func TestArchiveFile(t *testing.T) {
+	c, _ := testCollector(t, newFakeOsu(t), newMemStore())
+	c.Config.ArchiveDir = t.TempDir()
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	archived := func() []string {
+		files, _ := filepath.Glob(filepath.Join(c.Config.ArchiveDir, "failed", "*"))
+		return files
+	}
+
+	for _, keep := range []bool{false, true} {
+		archive, err := c.archiveFile("failed", "scores_2025_01", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive.Write([]byte("id\n1\n"))
+		if err := archive.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Closed but not kept yet, the scores might still stay
+		if files := archived(); len(files) != 1 || filepath.Ext(files[0]) != ".tmp" {
+			t.Fatalf("expected only the unfinished file, got %v", files)
+		}
+
+		if !keep {
+			archive.Abort()
+			if files := archived(); len(files) != 0 {
+				t.Fatalf("expected the aborted archive to be removed, got %v", files)
+			}
+			continue
+		}
+
+		if err := archive.Keep(); err != nil {
+			t.Fatal(err)
+		}
+		if files := archived(); len(files) != 1 || filepath.Base(files[0]) != "scores_2025_01_20250101T000000.csv.gz" {
+			t.Fatalf("expected the kept archive under its final name, got %v", files)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+// Where a stored score was collected from
+const (
+	SourceFeed     uint8 = iota // 0 - global /scores feed
+	SourceRecent                // 1 - /users/{id}/scores/recent
+	SourceBest                  // 2 - /users/{id}/scores/best
+	SourceFirsts                // 3 - /users/{id}/scores/firsts
+	SourceBackfill              // 4 - inserted by reconciliation
+)
+
+// FetchScores stores the next page of the global score feed and queues an
+// update for every player in it.
+func (c *Collector) FetchScores(ctx context.Context) {
+	scores, err := c.API.GetScores(ctx, c.cursor)
+	if err != nil {
+		if c.quarantinePayload(err) {
+			return
+		}
+
+		if errors.Is(err, osuapi.ErrInvalid) {
+			c.Logger.Warn("The cursor was rejected, starting over from the latest scores", "cursor", c.cursor, "error", err)
+			c.saveCursor("")
+			return
+		}
+
+		c.Logger.Error("Couldn't fetch scores", "endpoint", "/scores", "error", err)
+		return
+	}
+
+	start := time.Now()
+	var newUsers atomic.Int64
+	lastTime := time.Now()
+
+	if len(scores.Scores) == 0 {
+		return
+	}
+
+	defer func() {
+		c.Logger.Info("Fetched scores",
+			"scores", len(scores.Scores),
+			"duration", time.Since(start),
+			"new_users", newUsers.Load(),
+			"users", c.userCount.Load(),
+			"remaining", c.API.Remaining(),
+			"queue", len(c.userUpdater.in),
+			"priority", len(c.userUpdater.priority),
+			"queued", c.userUpdater.Len(),
+			"last_score", lastTime,
+		)
+	}()
+
+	var wg sync.WaitGroup
+
+	wg.Add(len(scores.Scores))
+
+	for _, score := range scores.Scores {
+		go func(s osuapi.Score) {
+			defer wg.Done()
+			priority := false
+			if !c.userCache.Exists(s.UserID) { //move to create?
+				user := &osuapi.UserExtended{ID: s.UserID}
+				if err := c.Store.CreateUser(ctx, user); err == nil {
+					newUsers.Add(1)
+					c.userCount.Add(1)
+					c.userCache.Add(s.UserID)
+					priority = true
+				}
+			}
+
+			go c.userUpdater.Queue(s.UserID, uint8(s.RulesetID), priority)
+			c.InsertScore(ctx, &s, SourceFeed)
+		}(score)
+		lastTime = score.EndedAt
+	}
+	wg.Wait()
+
+	if scores.CursorString != nil { //Moved it down here so it only writes cursor after inserts to not skip any by accient
+		c.saveCursor(*scores.CursorString)
+	}
+}
+
+// InsertScore stores a score unless it was stored within the last day.
+func (c *Collector) InsertScore(ctx context.Context, s *osuapi.Score, source uint8) error {
+	if _, exists := c.scoreCache.Get(s.ID); exists {
+		return nil
+	}
+
+	if err := c.Store.InsertScore(ctx, s, source); err != nil {
+		c.Logger.Error("Couldn't insert score", "score_id", s.ID, "user_id", s.UserID, "mode", s.RulesetID, "error", err)
+		return err
+	}
+
+	c.scoreCount.Add(1)
+	c.scoreMilestones(ctx, s, source)
+	return nil
+}
+
+// storeScores stores scores together with their beatmaps.
+func (c *Collector) storeScores(ctx context.Context, scores []osuapi.Score, source uint8) error {
+	for _, score := range scores {
+		if err := c.InsertScore(ctx, &score, source); err != nil {
+			return err
+		}
+		if score.Beatmap != nil && score.Beatmapset != nil {
+			c.Store.InsertBeatmap(ctx, score.Beatmap, score.Beatmapset)
+		}
+	}
+	return nil
+}
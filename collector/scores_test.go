@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchScores(t *testing.T) {
+	f := newFakeOsu(t)
+	db := testDB(t)
+	c, _ := testCollector(t, f, db)
+
+	f.Load(t, "/scores", "scores.json")
+
+	c.FetchScores(t.Context())
+
+	var stored int
+	if err := db.Pool().QueryRow(t.Context(), `SELECT COUNT(*) FROM scores WHERE source = $1`, SourceFeed).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != 2 {
+		t.Fatalf("expected 2 scores from the feed, got %d", stored)
+	}
+
+	var users int
+	if err := db.Pool().QueryRow(t.Context(), `SELECT COUNT(*) FROM users WHERE user_id IN (2, 3)`).Scan(&users); err != nil {
+		t.Fatal(err)
+	}
+	if users != 2 {
+		t.Fatalf("expected both players to be created, got %d", users)
+	}
+
+	if c.cursor != "eyJpZCI6NDAwMDAwMDAwMn0" {
+		t.Fatalf("cursor wasn't advanced: %q", c.cursor)
+	}
+
+	saved, err := os.ReadFile(c.Config.CursorFile)
+	if err != nil || string(saved) != c.cursor {
+		t.Fatalf("cursor wasn't written: %q %v", saved, err)
+	}
+
+	// Fetching the same page again must not duplicate anything
+	c.FetchScores(t.Context())
+
+	if err := db.Pool().QueryRow(t.Context(), `SELECT COUNT(*) FROM scores`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != 2 {
+		t.Fatalf("expected 2 scores after refetching, got %d", stored)
+	}
+}
+
+func TestFetchScoresMemory(t *testing.T) {
+	f := newFakeOsu(t)
+	store := newMemStore()
+	c, _ := testCollector(t, f, store)
+
+	f.Load(t, "/scores", "scores.json")
+
+	c.FetchScores(t.Context())
+	c.FetchScores(t.Context())
+
+	if len(store.scores) != 2 || store.scores[4000000002] != SourceFeed {
+		t.Fatalf("expected 2 scores from the feed, got %v", store.scores)
+	}
+
+	if len(store.users) != 2 || c.userCount.Load() != 2 {
+		t.Fatalf("expected both players to be created, got %d", len(store.users))
+	}
+
+	if c.cursor != "eyJpZCI6NDAwMDAwMDAwMn0" {
+		t.Fatalf("cursor wasn't advanced: %q", c.cursor)
+	}
+}
+
+func TestFetchScoresError(t *testing.T) {
+	f := newFakeOsu(t)
+	c, _ := testCollector(t, f, newMemStore())
+
+	f.Status("/scores", 500)
+	c.cursor = "unchanged"
+
+	c.FetchScores(t.Context())
+
+	if c.cursor != "unchanged" {
+		t.Fatalf("cursor changed on a failed fetch: %q", c.cursor)
+	}
+}
+
+func TestFetchScoresResetsInvalidCursor(t *testing.T) {
+	f := newFakeOsu(t)
+	c, _ := testCollector(t, f, newMemStore())
+
+	f.Error("/scores", 422, "invalid cursor")
+	c.cursor = "broken"
+
+	c.FetchScores(t.Context())
+
+	if c.cursor != "" {
+		t.Fatalf("cursor wasn't reset after a 422: %q", c.cursor)
+	}
+}
+
+func TestFetchScoresQuarantinesPayload(t *testing.T) {
+	f := newFakeOsu(t)
+	c, _ := testCollector(t, f, newMemStore())
+
+	f.Set(t, "/scores", []int{1, 2, 3})
+	c.cursor = "unchanged"
+
+	c.FetchScores(t.Context())
+
+	files, _ := filepath.Glob(filepath.Join(c.Config.QuarantineDir, "*_scores.json"))
+	if len(files) != 1 {
+		t.Fatalf("expected the payload to be quarantined, got %d files", len(files))
+	}
+
+	if c.cursor != "unchanged" {
+		t.Fatalf("cursor changed on an unparseable page: %q", c.cursor)
+	}
+}
@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"time"
+
+	discordwebhook "github.com/bensch777/discord-webhook-golang"
+	"github.com/calemy/advance-go/osuapi"
+)
+
+// UserMode is a single mode of a single player.
+type UserMode struct {
+	UserID int
+	Mode   int
+}
+
+type StoredScore struct {
+	ID     int
+	Time   time.Time
+	Source *int16 // nil for scores stored before sources were recorded
+}
+
+// Store persists everything the collector gathers. Postgres is the
+// implementation used in production.
+type Store interface {
+	InsertScore(ctx context.Context, s *osuapi.Score, source uint8) error
+	InsertBeatmap(ctx context.Context, b *osuapi.BeatmapExtended, s *osuapi.Beatmapset) error
+	KnownScores(ctx context.Context, ids []int) (map[int]struct{}, error)
+	StoredScores(ctx context.Context, id int, mode int, from time.Time, to time.Time) ([]StoredScore, error)
+	CreatePartitions(ctx context.Context, from time.Time, months int) (int, error)
+	ArchiveScores(ctx context.Context, rule RetentionRule, cutoff time.Time, open func(partition string) (Archive, error)) (int64, error)
+
+	CreateUser(ctx context.Context, u *osuapi.UserExtended) error
+	UpdateUser(ctx context.Context, u *osuapi.UserExtended) error
+	RestrictUser(ctx context.Context, id int) (string, error)
+	PeakStats(ctx context.Context, id int) (osuapi.UserStatistics, error)
+	LastUpdate(ctx context.Context, id int) (time.Time, error)
+	LoadUsers(ctx context.Context) ([]int, error)
+	LoadQueue(ctx context.Context) ([]UserMode, error)
+
+	HasHistory(ctx context.Context, id int, mode int) (bool, error)
+	ImportRankHistory(ctx context.Context, id int, mode int, ranks []int, daysAgo []int) error
+	UpdateHistory(ctx context.Context, id int, mode int, stats *osuapi.UserStatistics) error
+	UpdateBase(ctx context.Context, u *osuapi.UserExtended) error
+	LatestStats(ctx context.Context, id int, mode int) (*osuapi.UserStatistics, error)
+	TopPlay(ctx context.Context, id int, mode int, exclude int) (float64, error)
+	MarkMilestone(ctx context.Context, rule string, id int, mode int, key string) (bool, error)
+	SnapshotLeaderboards(ctx context.Context, day time.Time, mode int, size int) (int64, error)
+	AggregateActivity(ctx context.Context, batch int) (int64, error)
+	ComputeGains(ctx context.Context, period string, start time.Time, end time.Time, mode int) (int64, error)
+
+	SnapshotBest(ctx context.Context, id int, mode int, scores []osuapi.Score) error
+	PendingBest(ctx context.Context, days int) ([]UserMode, error)
+
+	UpdatePlaycounts(ctx context.Context, id int, counts []osuapi.BeatmapPlaycount) error
+	PendingPlaycounts(ctx context.Context, days int) ([]int, error)
+}
+
+// Archive receives the scores a retention rule removes from one partition.
+// Close is called once all of them were written and Keep once they were
+// deleted. Abort is called instead if either failed.
+type Archive interface {
+	io.WriteCloser
+	Keep() error
+	Abort()
+}
+
+// API is the part of the osu! API the collector uses. osuapi.OsuClient is the
+// implementation talking to osu.ppy.sh.
+type API interface {
+	GetScores(ctx context.Context, cursor string) (*osuapi.ScoresResponse, error)
+	GetUser(ctx context.Context, id int, mode int) (*osuapi.UserExtended, error)
+	GetUserScores(ctx context.Context, id int, kind string, mode string, opts osuapi.ScoreOptions) ([]osuapi.Score, error)
+	GetMostPlayed(ctx context.Context, id int, limit int, offset int) ([]osuapi.BeatmapPlaycount, error)
+	Remaining() int
+}
+
+// Notifier delivers Discord webhooks. WebhookWorker is the implementation.
+type Notifier interface {
+	Queue(hook discordwebhook.Hook)
+}
@@ -0,0 +1,328 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	discordwebhook "github.com/bensch777/discord-webhook-golang"
+	"github.com/calemy/advance-go/osuapi"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+type UserCache struct {
+	m  map[int]struct{}
+	mu sync.RWMutex
+}
+
+func (c *UserCache) Add(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[id] = struct{}{}
+}
+
+func (c *UserCache) Delete(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, id)
+}
+
+func (c *UserCache) Exists(id int) bool {
+	c.mu.RLock()
+	_, exists := c.m[id]
+	c.mu.RUnlock()
+	return exists
+}
+
+// AllScores pages through /users/{id}/scores/{kind} until either max scores
+// were collected or the API runs out of scores. A max of 0 means no limit.
+func (c *Collector) AllScores(ctx context.Context, id int, kind string, mode string, max int) ([]osuapi.Score, error) {
+	scores := make([]osuapi.Score, 0, 100)
+
+	for offset := 0; max <= 0 || offset < max; offset += 100 {
+		limit := 100
+		if max > 0 {
+			limit = min(limit, max-offset)
+		}
+
+		page, err := c.API.GetUserScores(ctx, id, kind, mode, osuapi.ScoreOptions{
+			Limit:        limit,
+			Offset:       offset,
+			IncludeFails: kind == "recent" && c.Config.IncludeFailed,
+		})
+		if err != nil {
+			return scores, err
+		}
+
+		scores = append(scores, page...)
+
+		if len(page) < limit {
+			break
+		}
+	}
+
+	return scores, nil
+}
+
+func (c *Collector) GetBest(ctx context.Context, id int, mode string) ([]osuapi.Score, error) {
+	return c.AllScores(ctx, id, "best", mode, 100)
+}
+
+func (c *Collector) GetFirsts(ctx context.Context, id int, mode string) ([]osuapi.Score, error) {
+	return c.AllScores(ctx, id, "firsts", mode, 0)
+}
+
+func (c *Collector) GetPinned(ctx context.Context, id int, mode string) ([]osuapi.Score, error) {
+	return c.AllScores(ctx, id, "pinned", mode, 0)
+}
+
+// KnownScores returns which of the given score ids are already stored.
+func (c *Collector) KnownScores(ctx context.Context, ids []int) (map[int]struct{}, error) {
+	known := make(map[int]struct{})
+	missing := make([]int, 0, len(ids))
+
+	for _, id := range ids {
+		if _, exists := c.scoreCache.Get(id); exists {
+			known[id] = struct{}{}
+			continue
+		}
+		missing = append(missing, id)
+	}
+
+	if len(missing) == 0 {
+		return known, nil
+	}
+
+	stored, err := c.Store.KnownScores(ctx, missing)
+	if err != nil {
+		return known, err
+	}
+
+	for id := range stored {
+		known[id] = struct{}{}
+	}
+
+	return known, nil
+}
+
+// UpdateScores stores the recent scores of the user. It keeps paging until it
+// reaches scores that are already stored or were set before since.
+func (c *Collector) UpdateScores(ctx context.Context, id int, mode string, since time.Time) error {
+	for offset := 0; ; offset += 100 {
+		data, err := c.API.GetUserScores(ctx, id, "recent", mode, osuapi.ScoreOptions{
+			Limit:        100,
+			Offset:       offset,
+			IncludeFails: c.Config.IncludeFailed,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(data) == 0 {
+			return nil
+		}
+
+		ids := make([]int, len(data))
+		for i, score := range data {
+			ids[i] = score.ID
+		}
+
+		known, err := c.KnownScores(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		// Scores from the global feed are usually stored already, so a single
+		// known score doesn't mean we caught up. A fully known page does.
+		caughtUp := len(data) < 100 || len(known) == len(data)
+
+		for _, score := range data {
+			if score.EndedAt.Before(since) {
+				caughtUp = true
+			}
+
+			if err := c.InsertScore(ctx, &score, SourceRecent); err != nil {
+				return err
+			}
+			c.scoreCache.Set(score.ID, struct{}{}, time.Until(score.EndedAt.Add(24*time.Hour)))
+			if score.Beatmap != nil && score.Beatmapset != nil {
+				c.Store.InsertBeatmap(ctx, score.Beatmap, score.Beatmapset)
+			}
+		}
+
+		if caughtUp {
+			return nil
+		}
+	}
+}
+
+func (c *Collector) Restrict(ctx context.Context, id int) error {
+	username, err := c.Store.RestrictUser(ctx, id)
+
+	c.userCount.Add(-1)
+
+	c.Logger.Info("User got restricted", "user_id", id, "username", username)
+
+	stats, err := c.Store.PeakStats(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	country, global := 0, 0
+	if stats.CountryRank != nil {
+		country = *stats.CountryRank
+	}
+	if stats.GlobalRank != nil {
+		global = *stats.GlobalRank
+	}
+
+	p := message.NewPrinter(language.English)
+
+	embed := discordwebhook.Embed{
+		Title:     fmt.Sprintf("%s (%d) just got restricted!", username, id),
+		Color:     0xD2042D,
+		Timestamp: time.Now(),
+		Thumbnail: discordwebhook.Thumbnail{
+			Url: fmt.Sprintf("https://a.ppy.sh/%d", id),
+		},
+		Footer: discordwebhook.Footer{
+			Text: fmt.Sprintf("Users tracked: %d", c.userCount.Load()),
+		},
+		Fields: []discordwebhook.Field{
+			{
+				Name:   "Country Rank",
+				Value:  p.Sprintf("%d", country),
+				Inline: true,
+			},
+			{
+				Name:   "Global Rank",
+				Value:  p.Sprintf("%d", global),
+				Inline: true,
+			},
+			{
+				Name:   "PP",
+				Value:  fmt.Sprintf("%.2f", stats.PP),
+				Inline: true,
+			},
+		},
+	}
+
+	hook := discordwebhook.Hook{
+		Username:   "Advance",
+		Avatar_url: "https://a.ppy.sh/9527931",
+		Embeds:     []discordwebhook.Embed{embed},
+	}
+
+	c.Restrictions.Queue(hook)
+
+	return nil
+}
+
+// ImportRankHistory backfills the global rank of the last 90 days from the
+// profile's rank_history. Days that already have an entry are left untouched.
+// The days are passed as how long ago they were, so the store counts them
+// from the same today the live stats are written to.
+func (c *Collector) ImportRankHistory(ctx context.Context, u *osuapi.UserExtended, mode int) error {
+	if u.RankHistory == nil || len(u.RankHistory.Data) == 0 {
+		return nil
+	}
+
+	last := len(u.RankHistory.Data) - 1
+
+	ranks := make([]int, 0, len(u.RankHistory.Data))
+	daysAgo := make([]int, 0, len(u.RankHistory.Data))
+
+	for i, rank := range u.RankHistory.Data {
+		if rank <= 0 { //Unranked on that day
+			continue
+		}
+		ranks = append(ranks, rank)
+		daysAgo = append(daysAgo, last-i)
+	}
+
+	if len(ranks) == 0 {
+		return nil
+	}
+
+	return c.Store.ImportRankHistory(ctx, u.ID, mode, ranks, daysAgo)
+}
+
+// userError handles a failed profile fetch. Only errors that can go away on
+// their own are returned, so the queue doesn't retry the others forever.
+func (c *Collector) userError(ctx context.Context, id int, mode int, err error) error {
+	log := c.Logger.With("user_id", id, "mode", mode)
+
+	switch {
+	case errors.Is(err, osuapi.ErrNotFound):
+		c.Restrict(ctx, id)
+		return nil
+	case errors.Is(err, osuapi.ErrForbidden):
+		log.Error("Couldn't update user, the client lacks the scope", "error", err)
+		return nil
+	case errors.Is(err, osuapi.ErrUnauthorized):
+		log.Error("Couldn't update user, the client credentials were rejected", "error", err)
+		return nil
+	case c.quarantinePayload(err):
+		return nil
+	}
+
+	log.Error("Couldn't update user", "error", err)
+
+	var apiErr *osuapi.APIError
+	if errors.As(err, &apiErr) && !apiErr.Retryable() {
+		return nil
+	}
+	return err
+}
+
+// UpdateUser refreshes the profile, recent scores and stats of a player in
+// every mode set in modes. It is the flush of the user queue.
+func (c *Collector) UpdateUser(ctx context.Context, id int, modes uint8) error {
+	var user *osuapi.UserExtended
+
+	since, err := c.Store.LastUpdate(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < 4; i++ {
+		if modes&(1<<i) != 0 {
+			fetched, err := c.API.GetUser(ctx, id, i)
+			if err != nil {
+				return c.userError(ctx, id, i, err)
+			}
+			user = fetched
+
+			c.UpdateScores(ctx, id, osuapi.ModeStr(i), since)
+
+			if exists, err := c.Store.HasHistory(ctx, id, i); err == nil && !exists {
+				if err := c.ImportRankHistory(ctx, user, i); err != nil {
+					c.Logger.Error("Couldn't import rank history", "user_id", id, "mode", i, "error", err)
+				}
+			}
+
+			var before *osuapi.UserStatistics
+			if c.hasMilestones(MilestoneCountryFirst, MilestoneGlobalRank, MilestonePP) {
+				if before, err = c.Store.LatestStats(ctx, id, i); err != nil {
+					c.Logger.Error("Couldn't look up the previous stats", "user_id", id, "mode", i, "error", err)
+				}
+			}
+
+			c.Store.UpdateHistory(ctx, id, i, user.Statistics)
+			c.userMilestones(ctx, user, i, before)
+			c.Logger.Debug("Updated user", "user_id", user.ID, "username", user.Username, "mode", i)
+		}
+	}
+
+	if user == nil {
+		return nil
+	}
+
+	c.statsCount.Add(1)
+	c.Store.UpdateUser(ctx, user)
+	c.Store.UpdateBase(ctx, user)
+	return nil
+}
@@ -0,0 +1,208 @@
+package collector
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+)
+
+func TestUpdateUser(t *testing.T) {
+	f := newFakeOsu(t)
+	db := testDB(t)
+	c, _ := testCollector(t, f, db)
+
+	f.Load(t, "/users/2", "user.json")
+	f.Load(t, "/users/2/scores/recent", "user_recent.json")
+
+	if err := db.CreateUser(t.Context(), &osuapi.UserExtended{ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.UpdateUser(t.Context(), 2, 1<<osuapi.ModeStd); err != nil {
+		t.Fatal(err)
+	}
+
+	var username, country string
+	if err := db.Pool().QueryRow(t.Context(), `SELECT username, country FROM users WHERE user_id = 2`).Scan(&username, &country); err != nil {
+		t.Fatal(err)
+	}
+	if username != "peppy" || country != "AU" {
+		t.Fatalf("user wasn't updated: %s %s", username, country)
+	}
+
+	var global, imported, total int
+	if err := db.Pool().QueryRow(t.Context(), `
+	SELECT
+		(SELECT global FROM stats WHERE user_id = 2 AND mode = 0 AND day = CURRENT_DATE),
+		(SELECT COUNT(*) FROM stats WHERE user_id = 2 AND mode = 0 AND imported),
+		(SELECT COUNT(*) FROM stats WHERE user_id = 2 AND mode = 0)
+	`).Scan(&global, &imported, &total); err != nil {
+		t.Fatal(err)
+	}
+
+	if global != 119990 {
+		t.Fatalf("expected today's rank to be 119990, got %d", global)
+	}
+
+	// rank_history has 5 days, one of them unranked, today comes from statistics
+	if imported != 3 || total != 4 {
+		t.Fatalf("expected 3 imported out of 4 days, got %d out of %d", imported, total)
+	}
+
+	var source int
+	if err := db.Pool().QueryRow(t.Context(), `SELECT source FROM scores WHERE score_id = 4000000003`).Scan(&source); err != nil {
+		t.Fatal(err)
+	}
+	if source != int(SourceRecent) {
+		t.Fatalf("expected recent score source, got %d", source)
+	}
+
+	var title string
+	if err := db.Pool().QueryRow(t.Context(), `SELECT title FROM beatmaps WHERE beatmap_id = 75`).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+	if title != "DISCO PRINCE" {
+		t.Fatalf("beatmap wasn't stored: %q", title)
+	}
+
+	// Imported days don't have a country rank to report
+	peak, err := db.PeakStats(t.Context(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *peak.CountryRank != 2345 || *peak.GlobalRank != 119990 {
+		t.Fatalf("expected the peak of the live stats, got %d %d", *peak.CountryRank, *peak.GlobalRank)
+	}
+}
+
+func TestUpdateUserRestricted(t *testing.T) {
+	f := newFakeOsu(t)
+	db := testDB(t)
+	c, hooks := testCollector(t, f, db)
+
+	if err := db.CreateUser(t.Context(), &osuapi.UserExtended{ID: 4, Username: "restricted"}); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Status("/users/4", http.StatusNotFound)
+
+	if err := c.UpdateUser(t.Context(), 4, 1<<osuapi.ModeStd); err != nil {
+		t.Fatal(err)
+	}
+
+	var restricted int
+	if err := db.Pool().QueryRow(t.Context(), `SELECT restricted FROM users WHERE user_id = 4`).Scan(&restricted); err != nil {
+		t.Fatal(err)
+	}
+	if restricted != 1 {
+		t.Fatal("user wasn't marked as restricted")
+	}
+
+	if hooks.Len() != 1 {
+		t.Fatalf("expected 1 restriction notification, got %d", hooks.Len())
+	}
+}
+
+func TestUpdateUserPaginatesRecent(t *testing.T) {
+	f := newFakeOsu(t)
+	db := testDB(t)
+	c, _ := testCollector(t, f, db)
+
+	f.Load(t, "/users/2", "user.json")
+
+	recent := make([]osuapi.Score, 150)
+	for i := range recent {
+		recent[i] = osuapi.Score{
+			ID:         5000000000 + 150 - i,
+			UserID:     2,
+			BeatmapID:  75,
+			RulesetID:  0,
+			Passed:     true,
+			Rank:       "A",
+			Beatmap:    &osuapi.BeatmapExtended{ID: 75, BeatmapsetID: 1},
+			Beatmapset: &osuapi.Beatmapset{ID: 1},
+			EndedAt:    time.Now().Add(time.Hour - time.Duration(i)*time.Second),
+		}
+	}
+	f.Set(t, "/users/2/scores/recent", recent)
+
+	if err := db.CreateUser(t.Context(), &osuapi.UserExtended{ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.UpdateUser(t.Context(), 2, 1<<osuapi.ModeStd); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored int
+	if err := db.Pool().QueryRow(t.Context(), `SELECT COUNT(*) FROM scores WHERE user_id = 2`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != 150 {
+		t.Fatalf("expected all 150 recent scores, got %d", stored)
+	}
+
+	if hits := f.Hits("/users/2/scores/recent"); hits != 2 {
+		t.Fatalf("expected 2 pages, got %d", hits)
+	}
+}
+
+func TestUpdateUserMemory(t *testing.T) {
+	f := newFakeOsu(t)
+	store := newMemStore()
+	c, hooks := testCollector(t, f, store)
+
+	f.Load(t, "/users/2", "user.json")
+	f.Load(t, "/users/2/scores/recent", "user_recent.json")
+	f.Status("/users/4", http.StatusNotFound)
+
+	if err := c.UpdateUser(t.Context(), 2, 1<<osuapi.ModeStd); err != nil {
+		t.Fatal(err)
+	}
+
+	if u := store.users[2]; u == nil || u.Username != "peppy" {
+		t.Fatalf("user wasn't updated: %+v", u)
+	}
+
+	key := UserMode{2, int(osuapi.ModeStd)}
+	if imported, total := len(store.imported[key]), len(store.stats[key]); imported != 3 || total != 4 {
+		t.Fatalf("expected 3 imported out of 4 days, got %d out of %d", imported, total)
+	}
+
+	if source, exists := store.scores[4000000003]; !exists || source != SourceRecent {
+		t.Fatalf("expected recent score to be stored, got %d %v", source, exists)
+	}
+
+	if b := store.beatmaps[75]; b == nil {
+		t.Fatal("beatmap wasn't stored")
+	}
+
+	if err := c.UpdateUser(t.Context(), 4, 1<<osuapi.ModeStd); err != nil {
+		t.Fatal(err)
+	}
+
+	if !store.restricted[4] || hooks.Len() != 1 {
+		t.Fatal("user wasn't restricted")
+	}
+}
+
+func TestUpdateUserErrors(t *testing.T) {
+	f := newFakeOsu(t)
+	c, _ := testCollector(t, f, newMemStore())
+	t.Chdir(t.TempDir())
+
+	f.Status("/users/3", http.StatusForbidden)
+	f.Status("/users/5", http.StatusServiceUnavailable)
+
+	// Scope problems don't go away by retrying, so the update is dropped
+	if err := c.UpdateUser(t.Context(), 3, 1<<osuapi.ModeStd); err != nil {
+		t.Fatalf("expected a 403 to be dropped, got %v", err)
+	}
+
+	if err := c.UpdateUser(t.Context(), 5, 1<<osuapi.ModeStd); !errors.Is(err, osuapi.ErrUnavailable) {
+		t.Fatalf("expected a 503 to be retried by the queue, got %v", err)
+	}
+}
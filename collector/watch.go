@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type watchedFile struct {
+	path    string
+	modTime time.Time
+	reload  func(path string) error
+}
+
+// Watcher reloads files whenever they change on disk or the process receives
+// SIGHUP. A failing reload keeps the previous state.
+type Watcher struct {
+	mu    sync.Mutex
+	files []*watchedFile
+}
+
+func NewWatcher() *Watcher {
+	return &Watcher{files: make([]*watchedFile, 0)}
+}
+
+// Watch registers a file to be reloaded with the given function.
+func (w *Watcher) Watch(path string, reload func(path string) error) {
+	f := &watchedFile{path: path, reload: reload}
+	if info, err := os.Stat(path); err == nil {
+		f.modTime = info.ModTime()
+	}
+
+	w.mu.Lock()
+	w.files = append(w.files, f)
+	w.mu.Unlock()
+}
+
+func (f *watchedFile) check(force bool) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return
+	}
+
+	if !force && info.ModTime().Equal(f.modTime) {
+		return
+	}
+
+	f.modTime = info.ModTime()
+
+	if err := f.reload(f.path); err != nil {
+		slog.Error("Couldn't reload, keeping the previous one", "component", "watcher", "file", f.path, "error", err)
+	}
+}
+
+// Reload checks every watched file, force reloading them even if they didn't
+// change.
+func (w *Watcher) Reload(force bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, f := range w.files {
+		f.check(force)
+	}
+}
+
+// Start polls the watched files every interval and listens for SIGHUP until
+// ctx is done.
+func (w *Watcher) Start(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.Reload(false)
+			case <-hup:
+				slog.Info("Received SIGHUP, reloading files", "component", "watcher")
+				w.Reload(true)
+			}
+		}
+	}()
+}
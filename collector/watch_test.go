@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxy.txt")
+	if err := os.WriteFile(path, []byte("a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	reloads := 0
+	var fail error
+	w := NewWatcher()
+	w.Watch(path, func(string) error {
+		reloads++
+		return fail
+	})
+
+	w.Reload(false)
+	if reloads != 0 {
+		t.Fatal("expected an unchanged file not to be reloaded")
+	}
+
+	touch := func(at time.Time) {
+		t.Helper()
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	touch(time.Now().Add(time.Minute))
+	w.Reload(false)
+	if reloads != 1 {
+		t.Fatalf("expected a changed file to be reloaded once, got %d", reloads)
+	}
+
+	// SIGHUP reloads even unchanged files
+	w.Reload(true)
+	if reloads != 2 {
+		t.Fatalf("expected a forced reload, got %d", reloads)
+	}
+
+	// A failing reload isn't retried until the file changes again
+	fail = errors.New("invalid")
+	touch(time.Now().Add(2 * time.Minute))
+	w.Reload(false)
+	w.Reload(false)
+	if reloads != 3 {
+		t.Fatalf("expected the failed reload to run once, got %d", reloads)
+	}
+}
@@ -0,0 +1,500 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	discordwebhook "github.com/bensch777/discord-webhook-golang"
+)
+
+// maxEmbeds is how many embeds Discord accepts in a single message.
+const maxEmbeds = 10
+
+var errInvalidHook = errors.New("invalid hook")
+
+// WebhookWorker delivers hooks to a Discord webhook in the background. Queue
+// never blocks, hooks wait in memory and, with a SpoolDir, on disk until
+// Discord accepted them. Hooks queued close together are sent as a single
+// message with up to 10 embeds.
+type WebhookWorker struct {
+	Name string
+	Link string // hooks are discarded if empty
+
+	// SpoolDir keeps undelivered hooks in <SpoolDir>/<Name>.json, so they
+	// survive restarts. It is read by Start, hooks queued before are kept
+	// from then on. Nothing is kept if empty.
+	SpoolDir string
+
+	// SpoolInterval is how often changes to the pending hooks are written to
+	// the spool file. They are written once more when the worker stops.
+	SpoolInterval time.Duration
+
+	// MaxPending bounds the hooks waiting for delivery, newer ones are
+	// dropped once it is reached.
+	MaxPending int
+
+	// Failed deliveries are retried forever, waiting Backoff before the first
+	// retry and twice as long before every following one, up to MaxBackoff.
+	// Ratelimits wait as long as Discord asks for instead.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	Client *http.Client
+	Logger *slog.Logger
+
+	mu      sync.Mutex
+	pending []discordwebhook.Hook
+	loaded  bool // the spool file is only written once it was read
+	dirty   bool // pending changed since the spool file was written
+	wake    chan struct{}
+	stopped chan struct{}
+
+	spoolMu sync.Mutex // keeps the spool file from being written concurrently
+
+	sent    atomic.Int64
+	retried atomic.Int64
+	dropped atomic.Int64
+}
+
+// WebhookStats counts the hooks a worker handled since it was created.
+type WebhookStats struct {
+	Sent    int64 `json:"sent"`
+	Retried int64 `json:"retried"`
+	Dropped int64 `json:"dropped"`
+	Pending int   `json:"pending"`
+}
+
+func NewWebhookWorker(name string, link string) *WebhookWorker {
+	return &WebhookWorker{
+		Name:          name,
+		Link:          link,
+		SpoolInterval: 5 * time.Second,
+		MaxPending:    10000,
+		Backoff:       time.Second,
+		MaxBackoff:    5 * time.Minute,
+		Client:        &http.Client{Timeout: 30 * time.Second},
+		Logger:        slog.Default().With("component", "webhook", "webhook", name),
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// Start loads the hooks left over from the last run and begins delivering
+// until ctx is cancelled. The hooks still pending then are persisted.
+func (w *WebhookWorker) Start(ctx context.Context) {
+	if w.Link == "" {
+		return
+	}
+
+	if err := w.load(); err != nil {
+		w.Logger.Error("Couldn't load undelivered hooks", "file", w.spoolFile(), "error", err)
+	}
+
+	w.stopped = make(chan struct{})
+	go w.run(ctx)
+}
+
+// Wait blocks until the worker stopped and persisted its pending hooks. It
+// returns right away if the worker was never started.
+func (w *WebhookWorker) Wait() {
+	if w.stopped != nil {
+		<-w.stopped
+	}
+}
+
+// Queue adds a hook to the pending ones without blocking.
+func (w *WebhookWorker) Queue(hook discordwebhook.Hook) {
+	if w.Link == "" {
+		return
+	}
+
+	w.mu.Lock()
+	for _, part := range splitHook(hook) {
+		if w.MaxPending > 0 && len(w.pending) >= w.MaxPending {
+			w.dropped.Add(1)
+			w.Logger.Warn("Dropped hook, too many are pending", "pending", len(w.pending))
+			continue
+		}
+		w.pending = append(w.pending, part)
+		w.dirty = true
+	}
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *WebhookWorker) Stats() WebhookStats {
+	w.mu.Lock()
+	pending := len(w.pending)
+	w.mu.Unlock()
+
+	return WebhookStats{
+		Sent:    w.sent.Load(),
+		Retried: w.retried.Load(),
+		Dropped: w.dropped.Load(),
+		Pending: pending,
+	}
+}
+
+func (w *WebhookWorker) run(ctx context.Context) {
+	spooled := make(chan struct{})
+	go w.spool(ctx, spooled)
+
+	// Only written once nothing changes the pending hooks anymore
+	defer func() {
+		<-spooled
+		w.flush()
+		close(w.stopped)
+	}()
+
+	backoff := w.Backoff
+	single := false
+	linkFailed := false
+
+	for {
+		hook, count, ok := w.next(ctx, single)
+		if !ok {
+			return
+		}
+
+		wait, err := sendHook(ctx, w.Client, w.Link, hook)
+		if ctx.Err() != nil {
+			// The hooks stay pending, whether Discord got them or not
+			return
+		}
+
+		var hookErr *WebhookError
+		isHookErr := errors.As(err, &hookErr)
+
+		switch {
+		case err == nil:
+			w.done(count)
+			w.sent.Add(int64(count))
+			backoff = w.Backoff
+			single = false
+
+			if linkFailed {
+				w.Logger.Info("Discord accepts the webhook again")
+				linkFailed = false
+			}
+
+		case isHookErr && hookErr.StatusCode == http.StatusTooManyRequests:
+			w.retried.Add(int64(count))
+			w.Logger.Warn("Ratelimited by Discord", "retry_after", hookErr.RetryAfter)
+			wait = max(wait, hookErr.RetryAfter)
+			if wait == 0 {
+				wait = w.Backoff
+			}
+
+		case isHookErr && (hookErr.StatusCode == http.StatusUnauthorized ||
+			hookErr.StatusCode == http.StatusForbidden || hookErr.StatusCode == http.StatusNotFound):
+			// The webhook was deleted or its link is wrong, which says nothing
+			// about the hooks. They are kept until the link is fixed.
+			w.retried.Add(int64(count))
+			if !linkFailed {
+				w.Logger.Error("Discord refused the webhook, keeping hooks until it works again", "error", err)
+				linkFailed = true
+			}
+			wait = max(wait, backoff)
+			backoff = min(backoff*2, w.MaxBackoff)
+
+		case errors.Is(err, errInvalidHook) || isHookErr && hookErr.StatusCode == http.StatusBadRequest:
+			// A batch can be rejected for a single bad embed or for being too
+			// big as a whole, so its hooks are retried one by one before
+			// anything gets dropped.
+			if count > 1 {
+				w.Logger.Warn("Discord rejected a batch, sending its hooks one by one", "hooks", count, "error", err)
+				single = true
+				continue
+			}
+
+			w.done(count)
+			w.dropped.Add(1)
+			w.Logger.Error("Discord rejected a hook, dropping it", "error", err)
+			single = false
+
+		default:
+			w.retried.Add(int64(count))
+			w.Logger.Warn("Couldn't deliver hooks, retrying", "hooks", count, "retry_in", max(wait, backoff), "error", err)
+			wait = max(wait, backoff)
+			backoff = min(backoff*2, w.MaxBackoff)
+		}
+
+		// Waits for the ratelimit to reset instead of running into a 429
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}
+}
+
+// next waits for pending hooks and merges as many of the oldest ones as fit
+// into a single message, only the oldest one if single is set. It returns
+// the message and how many hooks it holds, or false once ctx is cancelled.
+func (w *WebhookWorker) next(ctx context.Context, single bool) (discordwebhook.Hook, int, bool) {
+	for {
+		w.mu.Lock()
+		if len(w.pending) > 0 {
+			break
+		}
+		w.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return discordwebhook.Hook{}, 0, false
+		case <-w.wake:
+		}
+	}
+	defer w.mu.Unlock()
+
+	batch := w.pending[0]
+	batch.Embeds = append([]discordwebhook.Embed(nil), batch.Embeds...)
+	count := 1
+
+	for _, hook := range w.pending[1:] {
+		if single || !mergeable(batch, hook) || len(batch.Embeds)+len(hook.Embeds) > maxEmbeds {
+			break
+		}
+		batch.Embeds = append(batch.Embeds, hook.Embeds...)
+		count++
+	}
+
+	return batch, count, true
+}
+
+// done removes the oldest count hooks once they were handled.
+func (w *WebhookWorker) done(count int) {
+	w.mu.Lock()
+	w.pending = w.pending[count:]
+	w.dirty = true
+	w.mu.Unlock()
+}
+
+// spool writes the pending hooks to the spool file every SpoolInterval they
+// changed in, until ctx is cancelled. It closes done once it returned.
+func (w *WebhookWorker) spool(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	if w.spoolFile() == "" || w.SpoolInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.SpoolInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.flush()
+		}
+	}
+}
+
+// mergeable reports whether two hooks can be sent as one message. Only
+// embeds are merged, so the rest has to be the same.
+func mergeable(a discordwebhook.Hook, b discordwebhook.Hook) bool {
+	return a.Username == b.Username && a.Avatar_url == b.Avatar_url &&
+		a.Content == "" && b.Content == "" &&
+		len(a.Attachments) == 0 && len(b.Attachments) == 0
+}
+
+// splitHook splits hooks with more embeds than fit into a single message.
+func splitHook(hook discordwebhook.Hook) []discordwebhook.Hook {
+	if len(hook.Embeds) <= maxEmbeds {
+		return []discordwebhook.Hook{hook}
+	}
+
+	parts := make([]discordwebhook.Hook, 0, (len(hook.Embeds)+maxEmbeds-1)/maxEmbeds)
+	for start := 0; start < len(hook.Embeds); start += maxEmbeds {
+		part := hook
+		part.Embeds = hook.Embeds[start:min(start+maxEmbeds, len(hook.Embeds))]
+		if start > 0 {
+			part.Content = ""
+			part.Attachments = nil
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+func (w *WebhookWorker) spoolFile() string {
+	if w.SpoolDir == "" {
+		return ""
+	}
+	return filepath.Join(w.SpoolDir, w.Name+".json")
+}
+
+// flush writes the pending hooks to the spool file if they changed, or
+// removes it once there are none. The file is written without holding mu,
+// so Queue never waits for the disk.
+func (w *WebhookWorker) flush() {
+	path := w.spoolFile()
+	if path == "" {
+		return
+	}
+
+	w.spoolMu.Lock()
+	defer w.spoolMu.Unlock()
+
+	w.mu.Lock()
+	if !w.dirty || !w.loaded {
+		w.mu.Unlock()
+		return
+	}
+	hooks := append([]discordwebhook.Hook(nil), w.pending...)
+	w.dirty = false
+	w.mu.Unlock()
+
+	var err error
+	if len(hooks) == 0 {
+		if err = os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	} else {
+		err = writeSpool(path, hooks)
+	}
+
+	if err != nil {
+		w.Logger.Error("Couldn't persist undelivered hooks", "file", path, "error", err)
+
+		// Tried again with the next flush
+		w.mu.Lock()
+		w.dirty = true
+		w.mu.Unlock()
+	}
+}
+
+// writeSpool replaces the file at once, so a crash never leaves half of it.
+func writeSpool(path string, hooks []discordwebhook.Hook) error {
+	data, err := json.Marshal(hooks)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// load puts the hooks of the spool file in front of the pending ones.
+func (w *WebhookWorker) load() error {
+	path := w.spoolFile()
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var hooks []discordwebhook.Hook
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &hooks); err != nil {
+			return err
+		}
+	}
+
+	w.mu.Lock()
+	w.pending = append(hooks, w.pending...)
+	w.loaded = true
+	w.mu.Unlock()
+
+	if len(hooks) > 0 {
+		w.Logger.Info("Loaded undelivered hooks", "count", len(hooks))
+	}
+	return nil
+}
+
+// WebhookError is a response of Discord other than 2xx.
+type WebhookError struct {
+	StatusCode int
+	RetryAfter time.Duration // how long a ratelimit asks to wait, if any
+	Body       string
+}
+
+func (e *WebhookError) Error() string {
+	return fmt.Sprintf("discord responded with %d: %s", e.StatusCode, e.Body)
+}
+
+// sendHook posts a single message to a webhook. Responses other than 2xx
+// are returned as *WebhookError. It also returns how long to wait before the
+// next message if the ratelimit bucket is empty.
+func sendHook(ctx context.Context, client *http.Client, link string, hook discordwebhook.Hook) (time.Duration, error) {
+	payload, err := json.Marshal(hook)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", errInvalidHook, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, link, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var wait time.Duration
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		wait = parseSeconds(resp.Header.Get("X-RateLimit-Reset-After"))
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return wait, nil
+	}
+
+	hookErr := &WebhookError{StatusCode: resp.StatusCode, Body: string(body)}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		// The body is more precise than the header, which only has seconds
+		var limit struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		if json.Unmarshal(body, &limit) == nil && limit.RetryAfter > 0 {
+			hookErr.RetryAfter = time.Duration(limit.RetryAfter * float64(time.Second))
+		} else {
+			hookErr.RetryAfter = parseSeconds(resp.Header.Get("Retry-After"))
+		}
+	}
+
+	return wait, hookErr
+}
+
+// parseSeconds parses the fractional seconds of Discord's ratelimit headers.
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
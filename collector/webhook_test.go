@@ -0,0 +1,304 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	discordwebhook "github.com/bensch777/discord-webhook-golang"
+)
+
+// fakeDiscord records the messages posted to it. respond decides the
+// response to every message, 204 if it is nil.
+type fakeDiscord struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	messages []discordwebhook.Hook
+	respond  func(w http.ResponseWriter, hook discordwebhook.Hook) bool
+}
+
+func newFakeDiscord(t *testing.T) *fakeDiscord {
+	t.Helper()
+
+	d := &fakeDiscord{}
+	d.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var hook discordwebhook.Hook
+		if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		d.mu.Lock()
+		respond := d.respond
+		d.mu.Unlock()
+
+		if respond != nil && respond(w, hook) {
+			return
+		}
+
+		d.mu.Lock()
+		d.messages = append(d.messages, hook)
+		d.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(d.Close)
+
+	return d
+}
+
+func (d *fakeDiscord) Messages() []discordwebhook.Hook {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]discordwebhook.Hook(nil), d.messages...)
+}
+
+func testWebhook(d *fakeDiscord, dir string) *WebhookWorker {
+	w := NewWebhookWorker("test", d.URL)
+	w.SpoolDir = dir
+	w.SpoolInterval = 10 * time.Millisecond
+	w.Backoff = time.Millisecond
+	return w
+}
+
+func embedHook(title string) discordwebhook.Hook {
+	return discordwebhook.Hook{Username: "Advance", Embeds: []discordwebhook.Embed{{Title: title}}}
+}
+
+// waitFor polls until cond holds, failing the test after a few seconds.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhookBatching(t *testing.T) {
+	d := newFakeDiscord(t)
+	w := testWebhook(d, "")
+
+	// Queued before the worker runs, so they are batched deterministically
+	for i := range 12 {
+		w.Queue(embedHook(string(rune('a' + i))))
+	}
+	w.Queue(discordwebhook.Hook{Username: "Other", Embeds: []discordwebhook.Embed{{Title: "x"}}})
+	w.Start(t.Context())
+
+	waitFor(t, func() bool { return w.Stats().Sent == 13 })
+
+	messages := d.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	if len(messages[0].Embeds) != 10 || len(messages[1].Embeds) != 2 || messages[2].Username != "Other" {
+		t.Fatalf("unexpected batches: %d, %d, %q", len(messages[0].Embeds), len(messages[1].Embeds), messages[2].Username)
+	}
+	if messages[0].Embeds[0].Title != "a" || messages[1].Embeds[1].Title != "l" {
+		t.Fatal("embeds weren't sent in order")
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	d := newFakeDiscord(t)
+	w := testWebhook(d, "")
+
+	attempts := 0
+	d.respond = func(rw http.ResponseWriter, hook discordwebhook.Hook) bool {
+		attempts++
+		switch attempts {
+		case 1:
+			rw.WriteHeader(http.StatusInternalServerError)
+		case 2:
+			rw.Header().Set("Retry-After", "1")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			rw.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 0.01, "global": false}`))
+		default:
+			return false
+		}
+		return true
+	}
+
+	w.Start(t.Context())
+	start := time.Now()
+	w.Queue(embedHook("a"))
+
+	waitFor(t, func() bool { return w.Stats().Sent == 1 })
+
+	// retry_after of the body is preferred over the header
+	if time.Since(start) > 900*time.Millisecond {
+		t.Fatalf("expected the ratelimit of the body to be used, took %s", time.Since(start))
+	}
+	if stats := w.Stats(); stats.Retried != 2 || stats.Dropped != 0 || stats.Pending != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestWebhookRejected(t *testing.T) {
+	d := newFakeDiscord(t)
+	w := testWebhook(d, "")
+
+	// Discord rejects any message containing the bad embed
+	d.respond = func(rw http.ResponseWriter, hook discordwebhook.Hook) bool {
+		for _, embed := range hook.Embeds {
+			if embed.Title == "bad" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return true
+			}
+		}
+		return false
+	}
+
+	w.Queue(embedHook("a"))
+	w.Queue(embedHook("bad"))
+	w.Queue(embedHook("b"))
+	w.Start(t.Context())
+
+	waitFor(t, func() bool { return w.Stats().Pending == 0 })
+
+	if stats := w.Stats(); stats.Sent != 2 || stats.Dropped != 1 {
+		t.Fatalf("expected only the bad hook to be dropped, got %+v", stats)
+	}
+}
+
+func TestWebhookLinkFailure(t *testing.T) {
+	d := newFakeDiscord(t)
+	w := testWebhook(d, "")
+
+	var logs lockedBuffer
+	w.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	// A deleted webhook or bad token answers any message the same way
+	attempts := 0
+	d.respond = func(rw http.ResponseWriter, hook discordwebhook.Hook) bool {
+		attempts++
+		switch attempts {
+		case 1, 2:
+			rw.WriteHeader(http.StatusNotFound)
+		case 3:
+			rw.WriteHeader(http.StatusUnauthorized)
+		default:
+			return false
+		}
+		return true
+	}
+
+	w.Queue(embedHook("a"))
+	w.Queue(embedHook("b"))
+	w.Start(t.Context())
+
+	waitFor(t, func() bool { return w.Stats().Sent == 2 })
+
+	if stats := w.Stats(); stats.Dropped != 0 || stats.Retried != 6 {
+		t.Fatalf("expected the hooks to be kept, got %+v", stats)
+	}
+	if count := strings.Count(logs.String(), "Discord refused the webhook"); count != 1 {
+		t.Fatalf("expected the link failure to be logged once, got %d times", count)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe to write from the worker.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWebhookSpool(t *testing.T) {
+	dir := t.TempDir()
+
+	down := newFakeDiscord(t)
+	down.respond = func(rw http.ResponseWriter, hook discordwebhook.Hook) bool {
+		rw.WriteHeader(http.StatusBadGateway)
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	w := testWebhook(down, dir)
+	w.MaxBackoff = time.Hour
+	w.SpoolInterval = time.Hour
+	w.Start(ctx)
+	w.Queue(embedHook("a"))
+	w.Queue(embedHook("b"))
+
+	// Queue leaves the disk to the worker, which hasn't written yet
+	waitFor(t, func() bool { return w.Stats().Retried > 0 })
+	if _, err := os.Stat(filepath.Join(dir, "test.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected the spool file to be written later, got %v", err)
+	}
+
+	// Stopping interrupts the backoff and persists what is pending
+	cancel()
+	w.Wait()
+
+	if _, err := os.Stat(filepath.Join(dir, "test.json")); err != nil {
+		t.Fatalf("pending hooks weren't persisted: %v", err)
+	}
+
+	// A restart delivers them to the webhook that is up again
+	up := newFakeDiscord(t)
+	restarted := testWebhook(up, dir)
+	restarted.Queue(embedHook("c"))
+	restarted.Start(t.Context())
+	t.Cleanup(restarted.Wait)
+
+	waitFor(t, func() bool { return restarted.Stats().Sent == 3 })
+
+	messages := up.Messages()
+	if len(messages) != 1 || len(messages[0].Embeds) != 3 || messages[0].Embeds[0].Title != "a" {
+		t.Fatalf("expected the persisted hooks first, got %+v", messages)
+	}
+
+	waitFor(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, "test.json"))
+		return os.IsNotExist(err)
+	})
+}
+
+func TestWebhookQueueNeverBlocks(t *testing.T) {
+	d := newFakeDiscord(t)
+	w := testWebhook(d, "")
+	w.MaxPending = 5
+
+	done := make(chan struct{})
+	go func() {
+		for range 10 {
+			w.Queue(embedHook("a"))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Queue blocked without a running worker")
+	}
+
+	if stats := w.Stats(); stats.Pending != 5 || stats.Dropped != 5 {
+		t.Fatalf("expected 5 pending and 5 dropped hooks, got %+v", stats)
+	}
+}
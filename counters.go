@@ -1,5 +0,0 @@
-package main
-
-var userCount = 0
-var scoreCount = 0
-var statsCount = 0
\ No newline at end of file
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calemy/advance-go/collector"
+)
+
+func runExport(ctx context.Context, store *collector.Postgres, args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	table := fs.String("table", "", "table to export: scores, stats, stats_base, users or beatmaps")
+	format := fs.String("format", "csv", "csv, ndjson or parquet")
+	modes := fs.String("mode", "", "comma separated modes (0-3)")
+	from := fs.String("from", "", "start of the range (RFC3339 or 2006-01-02), inclusive")
+	to := fs.String("to", "", "end of the range (RFC3339 or 2006-01-02), exclusive")
+	countries := fs.String("country", "", "comma separated country codes")
+	users := fs.String("users", "", "comma separated user ids, or @file with one id per line")
+	out := fs.String("out", "", "file to write to, gzipped if it ends with .gz, defaults to stdout")
+	fs.Parse(args)
+
+	if *table == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts := collector.ExportOptions{
+		Table:  *table,
+		Format: *format,
+	}
+
+	var err error
+	if opts.Modes, err = parseInts(*modes); err != nil {
+		slog.Error("Invalid -mode", "error", err)
+		os.Exit(1)
+	}
+	if opts.From, err = parseTime(*from); err != nil {
+		slog.Error("Invalid -from", "error", err)
+		os.Exit(1)
+	}
+	if opts.To, err = parseTime(*to); err != nil {
+		slog.Error("Invalid -to", "error", err)
+		os.Exit(1)
+	}
+	if *countries != "" {
+		opts.Countries = strings.Split(strings.ToUpper(*countries), ",")
+	}
+
+	list := *users
+	if path, ok := strings.CutPrefix(list, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			slog.Error("Couldn't read -users", "error", err)
+			os.Exit(1)
+		}
+		list = strings.Join(strings.Fields(string(data)), ",")
+	}
+	if opts.Users, err = parseInts(list); err != nil {
+		slog.Error("Invalid -users", "error", err)
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			slog.Error("Couldn't create the output", "file", *out, "error", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+
+		if strings.HasSuffix(*out, ".gz") {
+			gz := gzip.NewWriter(f)
+			defer gz.Close()
+			w = gz
+		}
+	}
+
+	buffered := bufio.NewWriterSize(w, 1<<20)
+
+	count, err := store.Export(ctx, opts, buffered)
+	if err != nil {
+		slog.Error("Couldn't export", "error", err)
+		os.Exit(1)
+	}
+	if err := buffered.Flush(); err != nil {
+		slog.Error("Couldn't export", "error", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d rows\n", count)
+}
+
+func parseInts(list string) ([]int, error) {
+	if list == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(list, ",")
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+// parseTime accepts RFC3339 and plain dates in UTC. An empty string is the
+// zero time.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
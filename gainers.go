@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/calemy/advance-go/collector"
+)
+
+func runGainers(ctx context.Context, store *collector.Postgres, args []string) {
+	fs := flag.NewFlagSet("gainers", flag.ExitOnError)
+	period := fs.String("period", collector.GainsDay, "day, week or month")
+	at := fs.String("at", "", "any day within the period (RFC3339 or 2006-01-02), defaults to the current one")
+	mode := fs.Int("mode", 0, "mode (0-3)")
+	country := fs.String("country", "", "country code, defaults to global")
+	by := fs.String("by", "pp", "pp, rank or playcount")
+	limit := fs.Int("limit", 50, "number of players")
+	fs.Parse(args)
+
+	start, err := parseTime(*at)
+	if err != nil {
+		slog.Error("Invalid -at", "error", err)
+		os.Exit(1)
+	}
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	gains, err := store.TopGainers(ctx, collector.TopGainersOptions{
+		Period:  *period,
+		Start:   start,
+		Mode:    *mode,
+		Country: strings.ToUpper(*country),
+		By:      *by,
+		Limit:   *limit,
+	})
+	if err != nil {
+		slog.Error("Couldn't load gainers", "error", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tPLAYER\tCOUNTRY\tPP\tRANK\tPLAYCOUNT")
+	for i, g := range gains {
+		rank := "-"
+		if g.Rank != nil {
+			rank = fmt.Sprintf("%+d", *g.Rank)
+		}
+		fmt.Fprintf(w, "%d\t%s (%d)\t%s\t%+.2f\t%s\t%+d\n", i+1, g.Username, g.UserID, g.Country, g.PP, rank, g.Playcount)
+	}
+	w.Flush()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/calemy/advance-go/collector"
+)
+
+func runLeaderboard(ctx context.Context, store *collector.Postgres, args []string) {
+	fs := flag.NewFlagSet("leaderboard", flag.ExitOnError)
+	beatmap := fs.Int("beatmap", 0, "beatmap id")
+	mode := fs.Int("mode", 0, "mode (0-3)")
+	mods := fs.String("mods", "", "exact mod combination, e.g. HD,DT or NM, defaults to any")
+	failed := fs.Bool("failed", false, "include failed scores")
+	limit := fs.Int("limit", 50, "number of scores")
+	offset := fs.Int("offset", 0, "number of scores to skip")
+	fs.Parse(args)
+
+	if *beatmap == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts := collector.BeatmapLeaderboardOptions{
+		Beatmap:       *beatmap,
+		Mode:          *mode,
+		IncludeFailed: *failed,
+		Limit:         *limit,
+		Offset:        *offset,
+	}
+	if *mods != "" {
+		opts.Mods = strings.Split(*mods, ",")
+	}
+
+	scores, err := store.BeatmapLeaderboard(ctx, opts)
+	if err != nil {
+		slog.Error("Couldn't load leaderboard", "error", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tPLAYER\tSCORE\tACCURACY\tCOMBO\tPP\tMODS\tRANK\tDATE")
+	for _, s := range scores {
+		fmt.Fprintf(w, "%d\t%s (%d)\t%d\t%.2f%%\t%dx\t%.2f\t%s\t%s\t%s\n",
+			s.Position, s.Username, s.UserID, s.Score, s.Accuracy, s.MaxCombo, s.PP,
+			strings.Join(s.Mods, ""), s.Rank, s.Time.Format("2006-01-02"),
+		)
+	}
+	w.Flush()
+}
@@ -1,115 +1,311 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expvar"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
-	"sync"
+	"syscall"
 	"time"
 
 	_ "net/http/pprof"
 
-	discordwebhook "github.com/bensch777/discord-webhook-golang"
+	"github.com/calemy/advance-go/collector"
+	"github.com/calemy/advance-go/osuapi"
+	"github.com/joho/godotenv"
 	_ "github.com/joho/godotenv/autoload"
 )
 
-var includeFailed = 0
-var statsHook = NewWebhookWorker(os.Getenv("STATS_WEBHOOK"))
-var restrictHook = NewWebhookWorker(os.Getenv("RESTRICTED_WEBHOOK"))
-
 func main() {
-	var wg sync.WaitGroup
+	setupLogging()
+
+	// Everything stops on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	rpsStr := os.Getenv("REQUESTS_PER_SECOND")
 	rps := 5
-	if rpsStr != "" {
-		parsed, err := strconv.Atoi(rpsStr)
-		if err == nil && parsed != 0 {
-			rps = parsed
-		}
+	if parsed, err := strconv.Atoi(os.Getenv("REQUESTS_PER_SECOND")); err == nil && parsed != 0 {
+		rps = parsed
 	}
 
-	if os.Getenv("INCLUDE_FAILED") == "true" {
-		includeFailed = 1
+	store, err := collector.NewPostgres(ctx, os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		panic(err)
 	}
+	defer store.Close()
+	store.QueryTimeout = envDuration("QUERY_TIMEOUT", store.QueryTimeout)
+
+	watcher := collector.NewWatcher()
+
+	api := osuapi.NewOsuClient(newClient(ctx, rps, watcher), os.Getenv("CLIENT_ID"), os.Getenv("CLIENT_SECRET"))
+	api.BaseURL = envOr("OSU_API_URL", api.BaseURL)
+	api.AuthURL = envOr("OSU_AUTH_URL", api.AuthURL)
+	api.Timeout = envDuration("REQUEST_TIMEOUT", api.Timeout)
+
+	statsHook := collector.NewWebhookWorker("stats", os.Getenv("STATS_WEBHOOK"))
+	restrictHook := collector.NewWebhookWorker("restrictions", os.Getenv("RESTRICTED_WEBHOOK"))
 
-	client = NewLimitedClient(rps)
+	cfg := collector.DefaultConfig()
+	cfg.IncludeFailed = os.Getenv("INCLUDE_FAILED") == "true"
+	cfg.FetchTimeout = envDuration("FETCH_TIMEOUT", cfg.FetchTimeout)
+	cfg.UpdateTimeout = envDuration("UPDATE_TIMEOUT", cfg.UpdateTimeout)
+	if parsed, err := strconv.Atoi(os.Getenv("PARTITION_MONTHS")); err == nil && parsed > 0 {
+		cfg.PartitionMonths = parsed
+	}
+	if parsed, err := strconv.Atoi(os.Getenv("LEADERBOARD_SIZE")); err == nil && parsed > 0 {
+		cfg.LeaderboardSize = parsed
+	}
+	cfg.LeaderboardInterval = envDuration("LEADERBOARD_INTERVAL", cfg.LeaderboardInterval)
+	cfg.ActivityInterval = envDuration("ACTIVITY_INTERVAL", cfg.ActivityInterval)
+	cfg.GainsInterval = envDuration("GAINS_INTERVAL", cfg.GainsInterval)
+	cfg.MilestoneRules, err = collector.LoadMilestoneRules(envOr("MILESTONES_FILE", "milestones.json"))
+	if err != nil {
+		panic(err)
+	}
+	cfg.ArchiveDir = envOr("ARCHIVE_DIR", cfg.ArchiveDir)
+	cfg.RetentionRules, err = collector.LoadRetentionRules(envOr("RETENTION_FILE", "retention.json"))
+	if err != nil {
+		panic(err)
+	}
+	cfg.QuarantineDir = envOr("QUARANTINE_DIR", cfg.QuarantineDir)
+	if parsed, err := strconv.Atoi(os.Getenv("QUARANTINE_FILES")); err == nil && parsed > 0 {
+		cfg.QuarantineFiles = parsed
+	}
 
-	InitDB(os.Getenv("POSTGRES_URL"))
-	defer DB.Close()
+	c := collector.New(cfg, store, api, statsHook, restrictHook)
 
-	initCursor()
+	// Rules sharing a webhook share its worker, so they share its ratelimit
+	milestoneHooks := make(map[string]*collector.WebhookWorker)
+	c.Milestones = make(map[string]collector.Notifier)
+	for _, rule := range cfg.MilestoneRules {
+		if _, exists := milestoneHooks[rule.Webhook]; !exists {
+			milestoneHooks[rule.Webhook] = collector.NewWebhookWorker(milestoneHookName(rule.Webhook), rule.Webhook)
+			c.Milestones[rule.Webhook] = milestoneHooks[rule.Webhook]
+		}
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			runExport(ctx, store, os.Args[2:])
+		case "gainers":
+			runGainers(ctx, store, os.Args[2:])
+		case "leaderboard":
+			runLeaderboard(ctx, store, os.Args[2:])
+		case "migrate":
+			runMigrate(ctx, store, os.Args[2:])
+		case "reconcile":
+			runReconcile(ctx, c, os.Args[2:])
+		default:
+			fmt.Printf("Unknown command %q\n", os.Args[1])
+			os.Exit(2)
+		}
+		return
+	}
+
+	if os.Getenv("AUTO_MIGRATE") != "false" {
+		if _, err := store.Migrate(ctx); err != nil {
+			panic(err)
+		}
+	}
+
+	watcher.Watch(".env", func(path string) error {
+		return reloadEnv(api, path)
+	})
+	watcher.Start(ctx, 5*time.Second)
 	go func() {
 		http.ListenAndServe("localhost:6060", nil)
 	}()
 
-	if os.Getenv("ENABLE_WEBHOOK") != "true" {
-		statsHook.Link = ""
-		restrictHook.Link = ""
+	hooks := []*collector.WebhookWorker{statsHook, restrictHook}
+	for _, hook := range milestoneHooks {
+		hooks = append(hooks, hook)
 	}
 
-	statsHook.Start()
-	restrictHook.Start()
+	// Undelivered hooks are kept in WEBHOOK_SPOOL_DIR across restarts
+	spoolDir := envOr("WEBHOOK_SPOOL_DIR", "webhooks")
+	for _, hook := range hooks {
+		if os.Getenv("ENABLE_WEBHOOK") != "true" {
+			hook.Link = ""
+		}
+		hook.SpoolDir = spoolDir
+		hook.Start(ctx)
+	}
 
-	userUpdater.Workers(20)
-	userUpdater.Start()
+	expvar.Publish("webhooks", expvar.Func(func() any {
+		stats := make(map[string]collector.WebhookStats, len(hooks))
+		for _, hook := range hooks {
+			stats[hook.Name] = hook.Stats()
+		}
+		return stats
+	}))
 
-	loadUsers()
-	loadQueue()
+	if err := c.Start(ctx); err != nil {
+		panic(err)
+	}
 
-	fetchScores() // 4 * 1 Ratelimit -> 4 -> 604
+	if os.Getenv("ENABLE_BEST_SCORES") == "true" {
+		days := 7
+		if parsed, err := strconv.Atoi(os.Getenv("BEST_INTERVAL")); err == nil && parsed > 0 {
+			days = parsed
+		}
 
-	go func() {
-		defer wg.Done()
-		ticker := time.NewTicker(15 * time.Second)
-		defer ticker.Stop()
+		c.StartBest(ctx, days)
+	}
 
-		for range ticker.C {
-			fetchScores()
+	if os.Getenv("ENABLE_PLAYCOUNTS") == "true" {
+		days := 7
+		if parsed, err := strconv.Atoi(os.Getenv("PLAYCOUNTS_INTERVAL")); err == nil && parsed > 0 {
+			days = parsed
 		}
-	}()
 
-	go func() {
-		now := time.Now()
-		nextHour := now.Truncate(time.Hour).Add(time.Hour)
-		time.Sleep(time.Until(nextHour))
-
-		ticker := time.NewTicker(time.Hour)
-		defer ticker.Stop()
-
-		for {
-			embed := discordwebhook.Embed{
-				Title:     "Update Stats",
-				Color:     0x86DC3D,
-				Timestamp: time.Now(),
-				Footer: discordwebhook.Footer{
-					Text: fmt.Sprintf("Users tracked: %d", userCount),
-				},
-				Fields: []discordwebhook.Field{
-					{
-						Name:   "Scores Stored",
-						Value:  fmt.Sprintf("%d", scoreCount),
-						Inline: true,
-					},
-					{
-						Name:   "Stats Updated",
-						Value:  fmt.Sprintf("%d", statsCount),
-						Inline: true,
-					},
-				},
-			}
-			hook := discordwebhook.Hook{
-				Username:   "Advance",
-				Avatar_url: "https://a.ppy.sh/9527931",
-				Embeds:     []discordwebhook.Embed{embed},
-			}
-			statsHook.Queue(hook)
-			scoreCount = 0
-			statsCount = 0
-			<-ticker.C
+		perMinute := 30
+		if parsed, err := strconv.Atoi(os.Getenv("PLAYCOUNTS_PER_MINUTE")); err == nil && parsed > 0 {
+			perMinute = parsed
 		}
-	}()
 
-	select {} // block forever (or start server)
+		c.StartPlaycounts(ctx, days, perMinute)
+	}
+
+	if os.Getenv("ENABLE_LEADERBOARDS") == "true" {
+		c.StartLeaderboards(ctx)
+	}
+
+	if os.Getenv("ENABLE_ACTIVITY") == "true" {
+		c.StartActivity(ctx)
+	}
+
+	if os.Getenv("ENABLE_GAINS") == "true" {
+		c.StartGains(ctx)
+	}
+
+	if len(cfg.RetentionRules) > 0 {
+		c.StartRetention(ctx)
+	}
+
+	<-ctx.Done()
+	slog.Info("Shutting down")
+
+	for _, hook := range hooks {
+		hook.Wait()
+	}
+}
+
+// newClient creates the rate limited client, routed through the proxies in
+// proxy.txt when ENABLE_PROXY is set. The proxies are monitored until ctx is
+// cancelled.
+func newClient(ctx context.Context, rps int, watcher *collector.Watcher) *osuapi.Client {
+	var transport http.RoundTripper
+	if dir := os.Getenv("RECORD_FIXTURES"); dir != "" {
+		transport = &osuapi.RecordTransport{Dir: dir}
+	}
+
+	client := osuapi.NewClient(rps, transport)
+
+	if os.Getenv("ENABLE_PROXY") != "true" {
+		return client
+	}
+
+	proxyRPS := rps
+	if parsed, err := strconv.Atoi(os.Getenv("PROXY_REQUESTS_PER_SECOND")); err == nil && parsed > 0 {
+		proxyRPS = parsed
+	}
+
+	proxies, err := osuapi.ReadProxyFile("proxy.txt")
+	if err != nil {
+		panic(err)
+	}
+
+	rotator, err := osuapi.NewProxyRotator(proxies, proxyRPS)
+	if err != nil {
+		panic(err)
+	}
+
+	slog.Info("Registered proxies", "count", rotator.Len())
+
+	expvar.Publish("proxies", expvar.Func(func() any {
+		return rotator.Stats()
+	}))
+	go rotator.Monitor(ctx)
+
+	watcher.Watch("proxy.txt", rotator.Reload)
+
+	client.UseProxies(rotator)
+	return client
+}
+
+// reloadEnv applies changed credentials from the .env file. The new
+// credentials have to log in successfully before they replace the old ones.
+func reloadEnv(api *osuapi.OsuClient, path string) error {
+	env, err := godotenv.Read(path)
+	if err != nil {
+		return err
+	}
+
+	id, secret := env["CLIENT_ID"], env["CLIENT_SECRET"]
+	if id == "" || secret == "" {
+		return errors.New("CLIENT_ID and CLIENT_SECRET are required")
+	}
+
+	if id == os.Getenv("CLIENT_ID") && secret == os.Getenv("CLIENT_SECRET") {
+		return nil
+	}
+
+	if err := api.SetCredentials(context.Background(), id, secret); err != nil {
+		return err
+	}
+
+	os.Setenv("CLIENT_ID", id)
+	os.Setenv("CLIENT_SECRET", secret)
+
+	slog.Info("Reloaded client credentials")
+	return nil
+}
+
+// setupLogging configures the default logger from LOG_LEVEL (debug, info,
+// warn, error) and LOG_FORMAT (text, json).
+func setupLogging() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(envOr("LOG_LEVEL", "info"))); err != nil {
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if os.Getenv("LOG_FORMAT") == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+
+	slog.SetDefault(slog.New(handler))
+}
+
+// milestoneHookName names the worker of a milestone webhook after its url,
+// so its spool file stays the same however the rules using it change.
+func milestoneHookName(link string) string {
+	sum := sha256.Sum256([]byte(link))
+	return "milestones_" + hex.EncodeToString(sum[:4])
+}
+
+// envDuration parses a duration like 30s or 5m, falling back on anything
+// that isn't one.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if parsed, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return parsed
+	}
+	return fallback
+}
+
+func envOr(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi"
+	"github.com/calemy/advance-go/osuapi/osuapitest"
+)
+
+func TestReloadEnvRejectsCredentials(t *testing.T) {
+	server := osuapitest.New(t)
+	server.Credentials("id", "secret")
+	server.Set(t, "/users/2", osuapi.UserExtended{ID: 2, Username: "peppy"})
+
+	t.Setenv("CLIENT_ID", "id")
+	t.Setenv("CLIENT_SECRET", "secret")
+
+	api := osuapi.NewOsuClient(osuapi.NewClient(100, nil), "id", "secret")
+	api.BaseURL = server.BaseURL
+	api.AuthURL = server.AuthURL
+	api.Backoff = time.Millisecond
+
+	path := filepath.Join(t.TempDir(), ".env")
+	write := func(env string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(env), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, env := range []string{
+		"CLIENT_ID=id\nCLIENT_SECRET=wrong\n", // rejected by the token endpoint
+		"CLIENT_ID=id\n",                      // incomplete
+	} {
+		write(env)
+		if err := reloadEnv(api, path); err == nil {
+			t.Fatalf("expected %q to be rejected", env)
+		}
+	}
+
+	// The previous credentials stay in use
+	if os.Getenv("CLIENT_SECRET") != "secret" {
+		t.Fatal("expected the environment to keep the previous credentials")
+	}
+	if _, err := api.Fetch(t.Context(), "/users/2"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Credentials("new", "secret")
+	write("CLIENT_ID=new\nCLIENT_SECRET=secret\n")
+	if err := reloadEnv(api, path); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("CLIENT_ID") != "new" {
+		t.Fatal("expected the accepted credentials to be applied")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/calemy/advance-go/collector"
+)
+
+const migrateUsage = `usage: advance-go migrate [-drop-data] [command]
+
+commands:
+  up        apply every pending migration (default)
+  down      roll back the newest applied migration
+  to <n>    migrate up or down to version n, 0 rolls back everything
+  status    list the migrations and when they were applied
+
+Rolling back migration 0001 drops every table along with all collected data,
+so it is refused unless -drop-data is passed.`
+
+func runMigrate(ctx context.Context, store *collector.Postgres, args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dropData := fs.Bool("drop-data", false, "allow rolling back migration 0001, which deletes all data")
+	fs.Usage = func() { fmt.Println(migrateUsage) }
+
+	// The flag may come before or after the command
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	command := "up"
+	if len(positional) > 0 {
+		command = positional[0]
+	}
+
+	var (
+		count int
+		err   error
+	)
+
+	switch command {
+	case "up":
+		count, err = store.Migrate(ctx)
+	case "down":
+		var target int
+		if target, err = previousVersion(ctx, store); err == nil && target >= 0 {
+			refuseDataLoss(target, *dropData)
+			count, err = store.MigrateTo(ctx, target)
+		}
+	case "to":
+		if len(positional) < 2 {
+			fmt.Println(migrateUsage)
+			os.Exit(2)
+		}
+		version, parseErr := strconv.Atoi(positional[1])
+		if parseErr != nil {
+			log.Fatalf("invalid version %q", positional[1])
+		}
+		refuseDataLoss(version, *dropData)
+		count, err = store.MigrateTo(ctx, version)
+	case "status":
+		printMigrations(ctx, store)
+		return
+	default:
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("failed to migrate: %v", err)
+	}
+
+	fmt.Printf("Ran %d migrations\n", count)
+}
+
+// refuseDataLoss exits unless the data was explicitly allowed to be dropped
+// when migrating to target rolls back migration 0001.
+func refuseDataLoss(target int, dropData bool) {
+	if target < 1 && !dropData {
+		log.Fatalf("rolling back migration 0001 drops every table and all collected data, pass -drop-data to do it anyway")
+	}
+}
+
+// previousVersion returns the version before the newest applied migration,
+// -1 if none is applied.
+func previousVersion(ctx context.Context, store *collector.Postgres) (int, error) {
+	migrations, err := store.ListMigrations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	current, previous := -1, 0
+	for _, m := range migrations {
+		if m.AppliedAt.IsZero() {
+			continue
+		}
+		if current >= 0 {
+			previous = current
+		}
+		current = m.Version
+	}
+
+	if current < 0 {
+		return -1, nil
+	}
+	return previous, nil
+}
+
+func printMigrations(ctx context.Context, store *collector.Postgres) {
+	migrations, err := store.ListMigrations(ctx)
+	if err != nil {
+		log.Fatalf("failed to list migrations: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, m := range migrations {
+		applied := "pending"
+		if !m.AppliedAt.IsZero() {
+			applied = m.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", m.Version, m.Name, applied)
+	}
+	w.Flush()
+}
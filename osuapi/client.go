@@ -0,0 +1,264 @@
+package osuapi
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type Client struct {
+	Logger *slog.Logger
+
+	http       *http.Client
+	localLimit *rate.Limiter
+	remoteRL   *RemoteRL
+	inflight   chan struct{}
+	proxy      *ProxyRotator
+}
+
+// NewClient creates a client without proxies on top of the given transport,
+// nil meaning http.DefaultTransport.
+func NewClient(rps int, transport http.RoundTripper) *Client {
+	return &Client{
+		Logger: slog.Default().With("component", "osuapi"),
+
+		http: &http.Client{
+			Timeout:   15 * time.Second,
+			Transport: transport,
+		},
+
+		localLimit: rate.NewLimiter(rate.Limit(rps), rps),
+		remoteRL:   NewRemoteRL(),
+		inflight:   make(chan struct{}, 20),
+	}
+}
+
+// UseProxies routes every request through the rotator. Each proxy is limited
+// on its own instead of the client's limits.
+func (c *Client) UseProxies(rotator *ProxyRotator) {
+	c.http.Transport = rotator
+	c.proxy = rotator
+}
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	c.inflight <- struct{}{}
+	defer func() { <-c.inflight }()
+
+	// Every proxy egresses from its own IP and therefore has its own
+	// ratelimit on osu!'s side, so the budget is tracked per proxy.
+	var p *Proxy
+	if c.proxy != nil && c.proxy.Len() != 0 {
+		var err error
+		if req, p, err = c.proxy.Acquire(req); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := c.remoteRL.Check(req.Context()); err != nil {
+			return nil, err
+		}
+
+		if err := c.localLimit.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+
+	if p != nil {
+		p.Report(time.Since(start), resp, err)
+	}
+
+	if err != nil {
+		log := c.Logger
+		if p != nil {
+			log = p.Logger
+		}
+		log.Warn("Request failed", "endpoint", req.URL.Path, "error", err)
+		return nil, err
+	}
+
+	rl := c.remoteRL
+	if p != nil {
+		rl = p.remoteRL
+	}
+
+	rl.Update(resp)
+
+	if resp.StatusCode == 429 {
+		rl.TriggerFixed(time.Hour)
+		if p != nil {
+			p.Logger.Warn("Received 429, pausing the proxy for 1 hour", "endpoint", req.URL.Path)
+		} else {
+			c.Logger.Warn("Received 429, waiting for 1 hour", "endpoint", req.URL.Path)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, newAPIError(resp, body)
+	}
+
+	return resp, nil
+}
+
+// Remaining returns the last known remaining ratelimit, summed over all
+// proxies when they are in use.
+func (c *Client) Remaining() int {
+	if c.proxy == nil || c.proxy.Len() == 0 {
+		return c.remoteRL.Remaining()
+	}
+
+	remaining := 0
+	for _, p := range c.proxy.list() {
+		remaining += p.remoteRL.Remaining()
+	}
+	return remaining
+}
+
+type RemoteRL struct {
+	mu        sync.Mutex
+	waitUntil time.Time
+	waiting   bool
+	cond      *sync.Cond
+	timer     *time.Timer
+	remaining int
+	maxLimit  int
+}
+
+func NewRemoteRL() *RemoteRL {
+	rl := &RemoteRL{}
+	rl.cond = sync.NewCond(&rl.mu)
+	return rl
+}
+
+// Check blocks while requests are held back, or until ctx is done.
+func (rl *RemoteRL) Check(ctx context.Context) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if !rl.waiting {
+		return nil
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		rl.mu.Lock()
+		rl.cond.Broadcast()
+		rl.mu.Unlock()
+	})
+	defer stop()
+
+	rl.cond.Wait()
+	return ctx.Err()
+}
+
+// Waiting reports whether requests are currently held back.
+func (rl *RemoteRL) Waiting() bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.waiting
+}
+
+func (rl *RemoteRL) Remaining() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.remaining
+}
+
+func (rl *RemoteRL) TriggerFixed(d time.Duration) {
+	rl.mu.Lock()
+
+	if rl.waiting {
+		rl.mu.Unlock()
+		return
+	}
+
+	// Cancel any previous timer
+	if rl.timer != nil {
+		rl.timer.Stop()
+	}
+
+	rl.waiting = true
+	rl.waitUntil = time.Now().Add(d)
+	rl.timer = time.NewTimer(d)
+
+	// Dedicated wait goroutine
+	go func() {
+		<-rl.timer.C
+		rl.mu.Lock()
+		rl.waiting = false
+		rl.mu.Unlock()
+		rl.cond.Broadcast()
+	}()
+
+	rl.mu.Unlock()
+}
+
+func (rl *RemoteRL) Update(resp *http.Response) {
+	limitStr := resp.Header.Get("X-RateLimit-Limit")
+	remainStr := resp.Header.Get("X-RateLimit-Remaining")
+	// resetStr := resp.Header.Get("X-Ratelimit-Reset")
+
+	if limitStr == "" || remainStr == "" {
+		return
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		return
+	}
+
+	remain, err := strconv.Atoi(remainStr)
+	if err != nil {
+		return
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.maxLimit == 0 {
+		rl.maxLimit = limit
+	}
+
+	if remain > rl.maxLimit {
+		rl.maxLimit = remain
+	}
+
+	if remain > rl.remaining {
+		if remain > (rl.maxLimit / 2) {
+			rl.remaining = remain
+			rl.waiting = false
+			if rl.timer != nil {
+				rl.timer.Stop()
+				rl.cond.Broadcast()
+			}
+		}
+		return
+	}
+
+	rl.remaining = remain
+
+	if remain < 100 {
+		resetDur := 60 * time.Second
+		rl.waiting = true
+		rl.waitUntil = time.Now().Add(resetDur)
+
+		if rl.timer != nil {
+			rl.timer.Stop()
+		}
+
+		rl.timer = time.NewTimer(resetDur)
+
+		go func() {
+			<-rl.timer.C
+			rl.mu.Lock()
+			rl.waiting = false
+			rl.mu.Unlock()
+			rl.cond.Broadcast()
+		}()
+	}
+}
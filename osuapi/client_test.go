@@ -0,0 +1,225 @@
+package osuapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFetchLogsInOnce(t *testing.T) {
+	f := newFakeOsu(t)
+	f.Set(t, "/users/2", UserExtended{ID: 2, Username: "peppy"})
+
+	for i := 0; i < 3; i++ {
+		if _, err := f.Client.Fetch(t.Context(), "/users/2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if logins := f.Logins(); logins != 1 {
+		t.Fatalf("expected 1 login, got %d", logins)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	f := newFakeOsu(t)
+	f.Status("/users/1", http.StatusNotFound)
+	f.Status("/users/3", http.StatusInternalServerError)
+	f.Status("/users/4", http.StatusForbidden)
+	f.Status("/users/5", http.StatusBadRequest)
+
+	for path, expected := range map[string]error{
+		"/users/1": ErrNotFound,
+		"/users/3": ErrUnavailable,
+		"/users/4": ErrForbidden,
+		"/users/5": ErrFetch,
+	} {
+		if _, err := f.Client.Fetch(t.Context(), path); !errors.Is(err, expected) {
+			t.Fatalf("%s: expected %v, got %v", path, expected, err)
+		}
+	}
+}
+
+func TestFetchRetriesServerErrors(t *testing.T) {
+	f := newFakeOsu(t)
+	f.Status("/users/3", http.StatusBadGateway)
+
+	if _, err := f.Client.Fetch(t.Context(), "/users/3"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+
+	if hits := f.Hits("/users/3"); hits != 4 {
+		t.Fatalf("expected 1 request and 3 retries, got %d", hits)
+	}
+
+	f.Status("/users/1", http.StatusNotFound)
+	f.Client.Fetch(t.Context(), "/users/1")
+
+	if hits := f.Hits("/users/1"); hits != 1 {
+		t.Fatalf("expected no retries on 404, got %d requests", hits)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	f := newFakeOsu(t)
+	f.Error("/scores", http.StatusUnprocessableEntity, "invalid cursor")
+
+	_, err := f.Client.Fetch(t.Context(), "/scores")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+
+	if apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Endpoint != "/api/v2/scores" {
+		t.Fatalf("unexpected status or endpoint: %d %s", apiErr.StatusCode, apiErr.Endpoint)
+	}
+
+	if apiErr.Message != "invalid cursor" || apiErr.RateLimit != 1200 || apiErr.Remaining != 1199 {
+		t.Fatalf("unexpected details: %+v", apiErr)
+	}
+
+	if !errors.Is(err, ErrInvalid) || errors.Is(err, ErrFetch) || apiErr.Retryable() {
+		t.Fatal("a 422 should only match ErrInvalid and not be retryable")
+	}
+}
+
+func TestFetchTooManyRequests(t *testing.T) {
+	f := newFakeOsu(t)
+	f.Status("/scores", http.StatusTooManyRequests)
+
+	if _, err := f.Client.Fetch(t.Context(), "/scores"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+
+	if !f.Client.client.remoteRL.Waiting() {
+		t.Fatal("expected the client to hold back requests after a 429")
+	}
+}
+
+func TestFetchLowRatelimit(t *testing.T) {
+	f := newFakeOsu(t)
+	f.Set(t, "/users/2", UserExtended{ID: 2, Username: "peppy"})
+
+	if _, err := f.Client.Fetch(t.Context(), "/users/2"); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Remaining(51)
+
+	if _, err := f.Client.Fetch(t.Context(), "/users/2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining := f.Client.Remaining(); remaining != 50 {
+		t.Fatalf("expected 50 remaining, got %d", remaining)
+	}
+
+	if !f.Client.client.remoteRL.Waiting() {
+		t.Fatal("expected the client to wait with less than 100 remaining")
+	}
+}
+
+func ratelimitResponse(limit string, remaining string) *http.Response {
+	resp := &http.Response{Header: make(http.Header)}
+	resp.Header.Set("X-RateLimit-Limit", limit)
+	resp.Header.Set("X-RateLimit-Remaining", remaining)
+	return resp
+}
+
+func TestRemoteRLRecovers(t *testing.T) {
+	rl := NewRemoteRL()
+
+	rl.Update(ratelimitResponse("1200", "1100"))
+	if rl.Waiting() {
+		t.Fatal("should not wait with plenty remaining")
+	}
+
+	rl.Update(ratelimitResponse("1200", "90"))
+	if !rl.Waiting() {
+		t.Fatal("should wait with less than 100 remaining")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		rl.Check(context.Background())
+		close(done)
+	}()
+
+	rl.Update(ratelimitResponse("1200", "1199"))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Check didn't return after the ratelimit recovered")
+	}
+
+	if rl.Waiting() {
+		t.Fatal("should not wait after the ratelimit recovered")
+	}
+}
+
+func TestRemoteRLCheckCancels(t *testing.T) {
+	rl := NewRemoteRL()
+	rl.TriggerFixed(time.Hour)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := rl.Check(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to end with the context, got %v", err)
+	}
+}
+
+func TestRemoteRLIgnoresMissingHeaders(t *testing.T) {
+	rl := NewRemoteRL()
+	rl.Update(&http.Response{Header: make(http.Header)})
+	rl.Update(ratelimitResponse("abc", "1"))
+
+	if rl.Waiting() || rl.Remaining() != 0 {
+		t.Fatal("invalid headers should be ignored")
+	}
+}
+
+func TestRemoteRLTriggerFixed(t *testing.T) {
+	rl := NewRemoteRL()
+
+	start := time.Now()
+	rl.TriggerFixed(50 * time.Millisecond)
+	rl.TriggerFixed(time.Hour) // already waiting, must not extend
+
+	rl.Check(t.Context())
+
+	if waited := time.Since(start); waited < 50*time.Millisecond || waited > time.Second {
+		t.Fatalf("expected to wait about 50ms, waited %s", waited)
+	}
+}
+
+func TestSetCredentialsRejected(t *testing.T) {
+	f := newFakeOsu(t)
+	f.Credentials("id", "secret")
+	f.Set(t, "/users/2", UserExtended{ID: 2, Username: "peppy"})
+
+	if _, err := f.Client.Fetch(t.Context(), "/users/2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Client.SetCredentials(t.Context(), "id", "wrong"); err == nil {
+		t.Fatal("expected credentials that can't log in to be rejected")
+	}
+
+	// The old credentials and their token stay in use
+	if _, err := f.Client.Fetch(t.Context(), "/users/2"); err != nil {
+		t.Fatal(err)
+	}
+	if logins := f.Logins(); logins != 2 {
+		t.Fatalf("expected only the rejected login on top of the first one, got %d", logins)
+	}
+
+	f.Credentials("new", "secret")
+	if err := f.Client.SetCredentials(t.Context(), "new", "secret"); err != nil {
+		t.Fatal(err)
+	}
+}
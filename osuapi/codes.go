@@ -0,0 +1,105 @@
+package osuapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var ErrFetch = errors.New("something went wrong while fetching")
+var ErrNotFound = errors.New("this content could not be found")
+var ErrUnauthorized = errors.New("the client credentials were rejected")
+var ErrForbidden = errors.New("the token lacks the scope for this content")
+var ErrRateLimited = errors.New("remote rate limit reached (429)")
+var ErrUnavailable = errors.New("the api is currently unavailable")
+var ErrInvalid = errors.New("the request was rejected as invalid")
+
+// APIError is returned for every response that isn't a 200. It matches the
+// Err* value of its status code with errors.Is.
+type APIError struct {
+	StatusCode int
+	Endpoint   string
+	Message    string // the error field of osu!'s error JSON, if any
+	Body       []byte
+
+	RateLimit  int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Endpoint:   resp.Request.URL.Path,
+		Body:       body,
+	}
+
+	var payload struct {
+		Error any `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		if message, ok := payload.Error.(string); ok {
+			e.Message = message
+		}
+	}
+
+	e.RateLimit, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	e.Remaining, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return e
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s returned %d: %s", e.Endpoint, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s returned %d", e.Endpoint, e.StatusCode)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case http.StatusUnprocessableEntity:
+		return target == ErrInvalid
+	}
+
+	if e.StatusCode >= 500 {
+		return target == ErrUnavailable
+	}
+	return target == ErrFetch
+}
+
+// Retryable reports whether the same request can succeed later.
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// DecodeError is returned when a response doesn't match the expected types.
+// It keeps the payload so it can be inspected later.
+type DecodeError struct {
+	Endpoint string
+	Body     []byte
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("couldn't decode %s: %s", e.Endpoint, e.Err.Error())
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,69 @@
+package osuapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// GetScores returns the page of the global score feed after cursor. An empty
+// cursor starts at the most recent scores.
+func (o *OsuClient) GetScores(ctx context.Context, cursor string) (*ScoresResponse, error) {
+	var scores ScoresResponse
+	if err := o.get(ctx, "/scores?cursor_string="+url.QueryEscape(cursor), &scores); err != nil {
+		return nil, err
+	}
+	return &scores, nil
+}
+
+func (o *OsuClient) GetUser(ctx context.Context, id int, mode int) (*UserExtended, error) {
+	var user UserExtended
+	if err := o.get(ctx, fmt.Sprintf("/users/%d?mode=%d", id, mode), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserScores returns one page of the user's recent, best, firsts or pinned
+// scores.
+func (o *OsuClient) GetUserScores(ctx context.Context, id int, kind string, mode string, opts ScoreOptions) ([]Score, error) {
+	endpoint := fmt.Sprintf("/users/%d/scores/%s?mode=%s&limit=%d&offset=%d", id, kind, mode, opts.Limit, opts.Offset)
+	if opts.IncludeFails {
+		endpoint += "&include_fails=1"
+	}
+
+	var scores []Score
+	err := o.get(ctx, endpoint, &scores)
+	return scores, err
+}
+
+// GetBeatmaps returns up to 50 beatmaps at once. Unknown ids are left out.
+func (o *OsuClient) GetBeatmaps(ctx context.Context, ids []int) ([]BeatmapExtended, error) {
+	if len(ids) > 50 {
+		return nil, fmt.Errorf("at most 50 beatmaps can be requested at once, got %d", len(ids))
+	}
+
+	query := url.Values{}
+	for _, id := range ids {
+		query.Add("ids[]", strconv.Itoa(id))
+	}
+
+	var data BeatmapsResponse
+	err := o.get(ctx, "/beatmaps?"+query.Encode(), &data)
+	return data.Beatmaps, err
+}
+
+// GetBeatmapScores returns the top scores of a beatmap as the official
+// leaderboard shows them.
+func (o *OsuClient) GetBeatmapScores(ctx context.Context, id int, mode string, limit int) ([]Score, error) {
+	var data BeatmapScoresResponse
+	err := o.get(ctx, fmt.Sprintf("/beatmaps/%d/scores?mode=%s&limit=%d", id, mode, limit), &data)
+	return data.Scores, err
+}
+
+func (o *OsuClient) GetMostPlayed(ctx context.Context, id int, limit int, offset int) ([]BeatmapPlaycount, error) {
+	var counts []BeatmapPlaycount
+	err := o.get(ctx, fmt.Sprintf("/users/%d/beatmapsets/most_played?limit=%d&offset=%d", id, limit, offset), &counts)
+	return counts, err
+}
@@ -0,0 +1,70 @@
+package osuapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetUserScoresPages(t *testing.T) {
+	f := newFakeOsu(t)
+
+	scores := make([]Score, 150)
+	for i := range scores {
+		scores[i] = Score{ID: i + 1, UserID: 2}
+	}
+	f.Set(t, "/users/2/scores/best", scores)
+
+	page, err := f.Client.GetUserScores(t.Context(), 2, "best", "osu", ScoreOptions{Limit: 100, Offset: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page) != 50 || page[0].ID != 101 {
+		t.Fatalf("expected the last 50 scores, got %d starting at %d", len(page), page[0].ID)
+	}
+}
+
+func TestGetBeatmaps(t *testing.T) {
+	f := newFakeOsu(t)
+	f.Set(t, "/beatmaps", BeatmapsResponse{Beatmaps: []BeatmapExtended{{ID: 75}, {ID: 76}}})
+
+	beatmaps, err := f.Client.GetBeatmaps(t.Context(), []int{75, 76})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(beatmaps) != 2 || beatmaps[0].ID != 75 {
+		t.Fatalf("unexpected beatmaps: %+v", beatmaps)
+	}
+
+	if _, err := f.Client.GetBeatmaps(t.Context(), make([]int, 51)); err == nil {
+		t.Fatal("expected an error for more than 50 beatmaps")
+	}
+}
+
+func TestGetBeatmapScores(t *testing.T) {
+	f := newFakeOsu(t)
+	f.Set(t, "/beatmaps/75/scores", BeatmapScoresResponse{Scores: []Score{{ID: 1, BeatmapID: 75}}})
+
+	scores, err := f.Client.GetBeatmapScores(t.Context(), 75, "osu", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 1 || scores[0].BeatmapID != 75 {
+		t.Fatalf("unexpected scores: %+v", scores)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	f := newFakeOsu(t)
+	f.Set(t, "/scores", []int{1, 2, 3})
+
+	_, err := f.Client.GetScores(t.Context(), "")
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a DecodeError, got %v", err)
+	}
+	if string(decodeErr.Body) != "[1,2,3]" {
+		t.Fatalf("payload wasn't kept: %q", decodeErr.Body)
+	}
+}
@@ -0,0 +1,25 @@
+package osuapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/calemy/advance-go/osuapi/osuapitest"
+)
+
+type fakeOsu struct {
+	*osuapitest.Server
+	Client *OsuClient
+}
+
+func newFakeOsu(t *testing.T) *fakeOsu {
+	t.Helper()
+
+	f := &fakeOsu{Server: osuapitest.New(t)}
+	f.Client = NewOsuClient(NewClient(100, nil), "id", "secret")
+	f.Client.BaseURL = f.BaseURL
+	f.Client.AuthURL = f.AuthURL
+	f.Client.Backoff = time.Millisecond
+
+	return f
+}
@@ -0,0 +1,257 @@
+package osuapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type authToken struct {
+	Token     string `json:"access_token"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// OsuClient talks to the osu! API v2 with client credentials, on top of the
+// rate limited Client.
+type OsuClient struct {
+	// Base URLs of the osu! API and the token endpoint, swappable for tests
+	// or mirrors.
+	BaseURL string
+	AuthURL string
+
+	// Server errors are retried Retries times, waiting Backoff before the
+	// first retry and twice as long before every following one.
+	Retries int
+	Backoff time.Duration
+
+	// Timeout bounds every single request, including the time spent waiting
+	// for ratelimits. Zero disables it.
+	Timeout time.Duration
+
+	client *Client
+
+	mu     sync.Mutex
+	id     string
+	secret string
+	token  *string
+}
+
+func NewOsuClient(client *Client, id string, secret string) *OsuClient {
+	return &OsuClient{
+		BaseURL: "https://osu.ppy.sh/api/v2",
+		AuthURL: "https://auth.catboy.best/token",
+		Retries: 3,
+		Backoff: time.Second,
+		Timeout: time.Minute,
+		client:  client,
+		id:      id,
+		secret:  secret,
+	}
+}
+
+func (o *OsuClient) login(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	result, err := o.requestToken(ctx, o.id, o.secret)
+	if err != nil {
+		return err
+	}
+
+	o.token = &result
+	return nil
+}
+
+func (o *OsuClient) requestToken(ctx context.Context, id string, secret string) (string, error) {
+	payload := map[string]string{
+		"client_id":     id,
+		"client_secret": secret,
+	}
+
+	details, _ := json.Marshal(payload)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.AuthURL, bytes.NewBuffer(details))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return "", errors.New("Authentication not reachable.")
+		//TODO: Implement natively
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Authentication not reachable. Status: %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+
+	var result = &authToken{}
+	_ = json.Unmarshal(body, result)
+
+	return result.Token, nil
+}
+
+// SetCredentials replaces the client credentials. The new credentials have to
+// log in successfully before they replace the old ones.
+func (o *OsuClient) SetCredentials(ctx context.Context, id string, secret string) error {
+	o.mu.Lock()
+	unchanged := id == o.id && secret == o.secret
+	o.mu.Unlock()
+
+	if unchanged {
+		return nil
+	}
+
+	result, err := o.requestToken(ctx, id, secret)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	o.id, o.secret = id, secret
+	o.token = &result
+	o.mu.Unlock()
+
+	return nil
+}
+
+func (o *OsuClient) currentToken() *string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.token
+}
+
+// Request sends an authenticated GET request, logging in first if needed. A
+// 401 logs in again and retries once.
+func (o *OsuClient) Request(ctx context.Context, url string) (*http.Response, error) {
+	if o.currentToken() == nil {
+		if err := o.login(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := o.request(ctx, url)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	resp.Body.Close()
+
+	if err := o.login(ctx); err != nil {
+		return nil, err
+	}
+
+	return o.request(ctx, url)
+}
+
+func (o *OsuClient) request(ctx context.Context, url string) (*http.Response, error) {
+	var lastErr error
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *o.currentToken()))
+		req.Header.Set("x-api-version", "20220705")
+
+		resp, err := o.client.Do(req)
+
+		if err != nil {
+			if strings.Contains(err.Error(), "server sent GOAWAY") {
+				lastErr = err
+				time.Sleep(time.Duration(i+1) * time.Second)
+				continue
+			}
+			return nil, err
+		}
+
+		return resp, nil
+	}
+
+	return nil, lastErr
+}
+
+// Fetch returns the body of an API endpoint, relative to BaseURL. Responses
+// other than 200 are returned as *APIError, server errors are retried with
+// backoff first.
+func (o *OsuClient) Fetch(ctx context.Context, endpoint string) ([]byte, error) {
+	backoff := o.Backoff
+
+	for attempt := 0; ; attempt++ {
+		body, err := o.fetch(ctx, endpoint)
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode < 500 || attempt >= o.Retries {
+			return body, err
+		}
+
+		wait := max(backoff, apiErr.RetryAfter)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+func (o *OsuClient) fetch(ctx context.Context, endpoint string) ([]byte, error) {
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	resp, err := o.Request(ctx, o.BaseURL+endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, body)
+	}
+
+	return body, nil
+}
+
+func (o *OsuClient) get(ctx context.Context, endpoint string, v any) error {
+	body, err := o.Fetch(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return &DecodeError{Endpoint: endpoint, Body: body, Err: err}
+	}
+	return nil
+}
+
+// Remaining returns the last known remaining ratelimit.
+func (o *OsuClient) Remaining() int {
+	return o.client.Remaining()
+}
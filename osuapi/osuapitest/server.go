@@ -0,0 +1,239 @@
+// Package osuapitest provides an in-process stand-in for the osu! API and its
+// token endpoint, to test code built on osuapi without credentials.
+package osuapitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// Token is the access token the server hands out and expects.
+const Token = "fake-token"
+
+// Server serves responses set per path (without /api/v2). List responses are
+// paginated with limit and offset like the real API. Unknown paths respond
+// with 404, or an empty list on paginated endpoints.
+type Server struct {
+	*httptest.Server
+
+	// BaseURL and AuthURL are what OsuClient.BaseURL and OsuClient.AuthURL
+	// have to be set to.
+	BaseURL string
+	AuthURL string
+
+	mu        sync.Mutex
+	bodies    map[string][]byte
+	status    map[string]int
+	messages  map[string]string
+	hits      map[string]int
+	limit     int
+	remaining int
+	logins    int
+	clientID  string
+	secret    string
+}
+
+// New starts a server that is closed when the test finishes.
+func New(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		bodies:    make(map[string][]byte),
+		status:    make(map[string]int),
+		messages:  make(map[string]string),
+		hits:      make(map[string]int),
+		limit:     1200,
+		remaining: 1200,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", s.token)
+	mux.HandleFunc("GET /api/v2/scores", s.serve(false))
+	mux.HandleFunc("GET /api/v2/users/{id}", s.serve(false))
+	mux.HandleFunc("GET /api/v2/users/{id}/scores/{kind}", s.serve(true))
+	mux.HandleFunc("GET /api/v2/users/{id}/beatmapsets/most_played", s.serve(true))
+	mux.HandleFunc("GET /api/v2/beatmaps", s.serve(false))
+	mux.HandleFunc("GET /api/v2/beatmaps/{id}/scores", s.serve(false))
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	s.BaseURL = s.URL + "/api/v2"
+	s.AuthURL = s.URL + "/token"
+
+	return s
+}
+
+// Set serves v as JSON on path.
+func (s *Server) Set(t testing.TB, path string, v any) {
+	t.Helper()
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	s.bodies[path] = body
+	s.mu.Unlock()
+}
+
+// Load serves a fixture from the testdata directory of the calling package
+// on path.
+func (s *Server) Load(t testing.TB, path string, fixture string) {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	s.bodies[path] = body
+	s.mu.Unlock()
+}
+
+// Status makes path respond with the given status code.
+func (s *Server) Status(path string, code int) {
+	s.mu.Lock()
+	s.status[path] = code
+	s.mu.Unlock()
+}
+
+// Error makes path respond with the given status code and error message.
+func (s *Server) Error(path string, code int, message string) {
+	s.mu.Lock()
+	s.status[path] = code
+	s.messages[path] = message
+	s.mu.Unlock()
+}
+
+// Remaining sets the ratelimit reported with the next response.
+func (s *Server) Remaining(remaining int) {
+	s.mu.Lock()
+	s.remaining = remaining
+	s.mu.Unlock()
+}
+
+// Hits returns how often path was requested.
+func (s *Server) Hits(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+// Credentials makes the token endpoint reject every client credentials but
+// the given ones with 401. Any credentials are accepted by default.
+func (s *Server) Credentials(id string, secret string) {
+	s.mu.Lock()
+	s.clientID, s.secret = id, secret
+	s.mu.Unlock()
+}
+
+// Logins returns how often a token was requested.
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	var login struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	json.NewDecoder(r.Body).Decode(&login)
+
+	s.mu.Lock()
+	s.logins++
+	rejected := s.clientID != "" && (login.ClientID != s.clientID || login.ClientSecret != s.secret)
+	s.mu.Unlock()
+
+	if rejected {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"access_token": Token, "expires_in": 86400})
+}
+
+func (s *Server) serve(paginated bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path[len("/api/v2"):]
+
+		s.mu.Lock()
+		s.hits[path]++
+		if s.remaining > 0 {
+			s.remaining--
+		}
+		remaining := s.remaining
+		code, forced := s.status[path]
+		message, hasMessage := s.messages[path]
+		body, exists := s.bodies[path]
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+		if r.Header.Get("Authorization") != "Bearer "+Token {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"authentication":"basic"}`))
+			return
+		}
+
+		if forced {
+			w.WriteHeader(code)
+			if hasMessage {
+				json.NewEncoder(w).Encode(map[string]string{"error": message})
+			} else {
+				w.Write([]byte(`{"error":null}`))
+			}
+			return
+		}
+
+		if !exists {
+			if paginated {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":null}`))
+			return
+		}
+
+		if paginated {
+			body = paginate(body, r)
+		}
+
+		w.Write(body)
+	}
+}
+
+func paginate(body []byte, r *http.Request) []byte {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return body
+	}
+
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = len(items)
+	}
+
+	offset = min(offset, len(items))
+	end := min(offset+limit, len(items))
+
+	page, _ := json.Marshal(items[offset:end])
+	return page
+}
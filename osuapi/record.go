@@ -0,0 +1,56 @@
+package osuapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RecordTransport saves the body of every API response to Dir, so real
+// payloads can be dropped into testdata and replayed by the tests. It is
+// enabled through RECORD_FIXTURES=<dir>.
+type RecordTransport struct {
+	Dir  string
+	Next http.RoundTripper
+}
+
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := os.MkdirAll(t.Dir, 0755); err == nil {
+		name := fmt.Sprintf("%d_%s.json", resp.StatusCode, fixtureName(req))
+		os.WriteFile(filepath.Join(t.Dir, name), body, 0644)
+	}
+
+	return resp, nil
+}
+
+// fixtureName turns /api/v2/users/2/scores/recent into users_2_scores_recent.
+func fixtureName(req *http.Request) string {
+	path := strings.TrimPrefix(req.URL.Path, "/api/v2")
+	path = strings.Trim(path, "/")
+	if path == "" {
+		path = "root"
+	}
+	return strings.ReplaceAll(path, "/", "_")
+}
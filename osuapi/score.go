@@ -0,0 +1,144 @@
+package osuapi
+
+import (
+	"time"
+)
+
+type ScoresResponse struct {
+	Scores       []Score `json:"scores"`
+	CursorString *string `json:"cursor_string"`
+}
+
+type Score struct { //We are currently using lazer metrics and yet not all of the ones listed below
+	Accuracy          float64         `json:"accuracy"`
+	BeatmapID         int             `json:"beatmap_id"`
+	BuildID           *int            `json:"build_id"`
+	ClassicTotalScore int             `json:"classic_total_score"`
+	EndedAt           time.Time       `json:"ended_at"`
+	HasReplay         bool            `json:"has_replay"`
+	ID                int             `json:"id"`
+	IsPerfectCombo    bool            `json:"is_perfect_combo"`
+	LegacyPerfect     bool            `json:"legacy_perfect"`
+	LegacyScoreID     *int            `json:"legacy_score_id"`
+	LegacyTotalScore  int             `json:"legacy_total_score"`
+	MaxCombo          int             `json:"max_combo"`
+	MaximumStatistics ScoreStatistics `json:"maximum_statistics"`
+	Mods              []Mod           `json:"mods"`
+	Passed            bool            `json:"passed"`
+	PlaylistItemID    *int            `json:"playlist_item_id"`
+	PP                float64         `json:"pp"`
+	Preserve          *bool           `json:"preserve"`
+	Processed         *bool           `json:"processed"`
+	Rank              string          `json:"rank"`
+	Ranked            *bool           `json:"ranked"`
+	RoomID            *int            `json:"room_id"`
+	RulesetID         int             `json:"ruleset_id"`
+	StartedAt         *time.Time      `json:"started_at"`
+	Statistics        ScoreStatistics `json:"statistics"`
+	TotalScore        int             `json:"total_score"`
+	Type              string          `json:"type"`
+	UserID            int             `json:"user_id"`
+
+	Beatmap    *BeatmapExtended `json:"beatmap"`
+	Beatmapset *Beatmapset      `json:"beatmapset"`
+}
+
+type ScoreStatistics struct { //Most of them are currently unused
+	Miss                int `json:"miss"`
+	Meh                 int `json:"meh"`
+	Ok                  int `json:"ok"`
+	Good                int `json:"good"`
+	Great               int `json:"great"`
+	Perfect             int `json:"perfect"`
+	SmallTickMiss       int `json:"small_tick_miss"`
+	SmallTickHit        int `json:"small_tick_hit"`
+	LargeTickMiss       int `json:"large_tick_miss"`
+	LargeTickHit        int `json:"large_tick_hit"`
+	SmallBonus          int `json:"small_bonus"`
+	LargeBonus          int `json:"large_bonus"`
+	IgnoreMiss          int `json:"ignore_miss"`
+	IgnoreHit           int `json:"ignore_hit"`
+	ComboBreak          int `json:"combo_break"`
+	SliderTailHit       int `json:"slider_tail_hit"`
+	LegacyComboIncrease int `json:"legacy_combo_increase"`
+}
+
+type BeatmapExtended struct {
+	ID           int `json:"id"`
+	BeatmapsetID int `json:"beatmapset_id"`
+	UserID       int `json:"user_id"`
+
+	ModeInt int `json:"mode_int"`
+
+	Ranked int `json:"ranked"`
+
+	Version          string  `json:"version"`
+	DifficultyRating float64 `json:"difficulty_rating"`
+
+	TotalLength int `json:"total_length"`
+	HitLength   int `json:"hit_length"`
+
+	Accuracy float64 `json:"accuracy"`
+	AR       float64 `json:"ar"`
+	CS       float64 `json:"cs"`
+	Drain    float64 `json:"drain"`
+
+	BPM *float64 `json:"bpm"`
+
+	Playcount int `json:"playcount"`
+	Passcount int `json:"passcount"`
+
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+type Beatmapset struct {
+	ID int `json:"id"`
+
+	Artist  string `json:"artist"`
+	Title   string `json:"title"`
+	Creator string `json:"creator"`
+
+	Status string `json:"status"`
+
+	UserID int `json:"user_id"`
+}
+
+const (
+	ModeStd   uint8 = iota // 0
+	ModeTaiko              // 1
+	ModeCatch              // 2
+	ModeMania              // 3
+)
+
+type Mod struct { //We do not support modifiers. I don't care. - v3 (Nanoo)
+	Acronym string `json:"acronym"`
+}
+
+func ModeStr(mode int) string {
+	switch mode {
+	case 0:
+		return "osu"
+	case 1:
+		return "taiko"
+	case 2:
+		return "fruits"
+	case 3:
+		return "mania"
+	default:
+		return "osu"
+	}
+}
+
+type ScoreOptions struct {
+	Limit        int
+	Offset       int
+	IncludeFails bool
+}
+
+type BeatmapsResponse struct {
+	Beatmaps []BeatmapExtended `json:"beatmaps"`
+}
+
+type BeatmapScoresResponse struct {
+	Scores []Score `json:"scores"`
+}
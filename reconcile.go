@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/calemy/advance-go/collector"
+)
+
+func runReconcile(ctx context.Context, c *collector.Collector, args []string) {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	id := fs.Int("user", 0, "user id to reconcile")
+	mode := fs.Int("mode", 0, "mode to reconcile (0-3)")
+	from := fs.String("from", "", "start of the range (RFC3339), defaults to 24 hours ago")
+	to := fs.String("to", "", "end of the range (RFC3339), defaults to now")
+	backfill := fs.Bool("backfill", false, "insert missing scores")
+	fs.Parse(args)
+
+	if *id == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	end := time.Now()
+	if *to != "" {
+		parsed, err := time.Parse(time.RFC3339, *to)
+		if err != nil {
+			log.Fatalf("invalid -to: %v", err)
+		}
+		end = parsed
+	}
+
+	start := end.Add(-24 * time.Hour)
+	if *from != "" {
+		parsed, err := time.Parse(time.RFC3339, *from)
+		if err != nil {
+			log.Fatalf("invalid -from: %v", err)
+		}
+		start = parsed
+	}
+
+	r, err := c.Reconcile(ctx, *id, *mode, start, end)
+	if err != nil {
+		log.Fatalf("failed to reconcile: %v", err)
+	}
+
+	r.Print(os.Stdout)
+
+	if *backfill {
+		inserted, err := c.Backfill(ctx, r)
+		if err != nil {
+			log.Fatalf("failed to backfill: %v", err)
+		}
+		fmt.Printf("Backfilled %d scores\n", inserted)
+	}
+}